@@ -3,8 +3,13 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	zapp "github.com/maoueh/zap-pretty"
 	"github.com/spf13/cobra"
@@ -45,28 +50,209 @@ func main() {
 			JSON looks like '{"severity":"INFO","timestamp":"2018-12-21T23:06:49.435919-05:00","caller":"c:0","message":"m"}'
 			and we support extra variations like 'time' instead of 'timestamp', etc.
 
+			### Go crash output
+
+			Go 'panic:', 'fatal error:' and goroutine dump outputs spanning multiple lines are detected and rendered as a
+			colored section: panic message, goroutines with their state and frames with function and file:line.
+
+			### Container logs
+
+			Raw container log files are unwrapped: Docker json-file lines like '{"log":"{...}\n","stream":"stderr","time":"..."}'
+			and CRI lines like '2024-12-18T14:27:49.160Z stderr F {...}' have their inner line prettified, lines split by
+			the container runtime are reassembled.
+
 			## Formats
 
 			The tool also has formatting options controlled via flags:
 
 			  - '--all' (ZAP_PRETTY_ALL)
 			    Show all fields that would normally be ignored by default like 'serviceContext', 'labels', etc.
+			    This disables the format's default hide profile but still honors '--hide-field'.
+
+			  - '--hide-field' (ZAP_PRETTY_HIDE_FIELD)
+			    Hide fields whose dotted path matches the glob pattern, like 'req.headers.*', can be repeated.
+			    In patterns, '*' matches anything but '.', '**' matches anything and '?' matches a single character.
+
+			  - '--only-field' (ZAP_PRETTY_ONLY_FIELD)
+			    Only show fields whose dotted path matches the glob pattern, like 'req.id', can be repeated.
+
+			  - '--filter' (ZAP_PRETTY_FILTER)
+			    Only print log lines matching the expression, like 'logger == "p2p" && fields.peer_count < 3' or 'msg =~ "timeout"'.
+			    Identifiers are level, logger, caller, msg, ts, stacktrace, errorVerbose, source, stream and fields (like
+			    'fields.req.id' or 'fields["weird-key"]'). Operators are ==, !=, <, <=, >, >=, =~, !~, &&, || and !, 'exists(fields.x)'
+			    reports if a value is present. Lines that are not log lines are always printed.
+
+			  - '--after-context, -A', '--before-context, -B' and '--context, -C' (ZAP_PRETTY_AFTER_CONTEXT, ...)
+			    Like grep, print N log lines after, before or around each line matching '--filter', non-contiguous
+			    groups are separated by a '--' line. '-A' and '-B' take precedence over '-C'.
+
+			  - '--highlight' (ZAP_PRETTY_HIGHLIGHT)
+			    Mark the matches of the regular expression in the message and field values without filtering lines,
+			    can be repeated. Prefix with 'field=~' to restrict to the fields whose dotted path matches the glob
+			    pattern, like 'req.path=~^/api', use 'msg=~' to only highlight the message.
+
+			  - '--dedupe' (ZAP_PRETTY_DEDUPE)
+			    Collapse consecutive log lines with the same level, logger and message into a single one followed by
+			    '(repeated N times over 12.3s)'. With '--dedupe-fields', fields must also be equal, except the ones whose
+			    dotted path matches a '--dedupe-ignore-field' glob pattern. The line is printed when a different one
+			    arrives or after '--dedupe-timeout' so that live output is not stalled.
+
+			  - '--max-rate' (ZAP_PRETTY_MAX_RATE) and '--sample' (ZAP_PRETTY_SAMPLE)
+			    Keep the output live during floods by sampling log lines like zap does, per level, logger and message.
+			    '--max-rate 100/s' prints at most 100 lines per key each second (unit can also be 'm', 'h' or a duration)
+			    and '--sample 1/100' then only prints one every 100 DEBUG lines. Dropped lines are reported by periodic
+			    '[dropped N lines from logger X]' notices.
+
+			  - '--summary' (ZAP_PRETTY_SUMMARY)
+			    Print statistics about the input to stderr once it ends: counts per level and per logger, the
+			    '--summary-top' most frequent messages, non-JSON and unparseable lines, first and last timestamps and
+			    lines per second. The statistics so far can also be printed on SIGUSR1 or by pressing Enter.
+
+			  - '--status-bar' (ZAP_PRETTY_STATUS_BAR)
+			    When the output is a terminal, show a status bar at its bottom with live statistics: lines per second,
+			    errors and warnings counts, time of the last error and the filters in effect.
+
+			  - '--tui' (ZAP_PRETTY_TUI)
+			    Browse the log file interactively instead of printing it: records are listed one per line, Enter expands
+			    the selected one to show all its fields and error details, '/' searches incrementally ('n'/'N' for next
+			    and previous matches), 'd', 'i', 'w' and 'e' toggle levels, 'F' follows the file as it grows and 'q' quits.
+			    Rendering flags like '--header-format' and '--filter' apply, '--summary' cannot be used with it.
+
+			  - '--source' (ZAP_PRETTY_SOURCE)
+			    Tag each line read from stdin with the source name, like 'api |', rendered in a color derived from the name
+			    so that processes sharing a terminal stay distinguishable. Log files (or commands through process
+			    substitution, like 'api=<(run api)') can also be given as arguments, their lines are then printed as they
+			    are read, each one tagged with its file name, '--source' cannot be used then. The name is available to
+			    '--filter' as 'source'.
+
+			  - '--prefix' (ZAP_PRETTY_PREFIX)
+			    Split the prefix added by log aggregating tools off each line and render it as the source tag, one of
+			    'compose' (like 'api-1  | {...}'), 'kubectl' ('kubectl logs --prefix', like '[pod/api-7f9c/api] {...}'),
+			    'stern' (like 'api-7f9c api {...}', only split off in front of a JSON object) or a regular expression
+			    matching the prefix whose 'source' named group is the source name, like '^(?P<source>\S+): ', an
+			    optional 'rest' named group marks where the rest of the line starts. Lines without the prefix are
+			    processed as is.
+
+			  - '--show-stream' (ZAP_PRETTY_SHOW_STREAM)
+			    Show the stream, like 'stderr', of the lines unwrapped from Docker json-file or CRI container logs. The
+			    stream is available to '--filter' as 'stream'.
+
+			  - '--go-test' (ZAP_PRETTY_GO_TEST)
+			    Read the output of 'go test -json': the output of the tests is unwrapped and prettified, test run, pass,
+			    fail and skip events are rendered as distinct headers and the output of each top-level test is grouped
+			    under its header, tests running in parallel being printed once the one being printed is done.
+
+			  - '--collapse-runtime-goroutines' (ZAP_PRETTY_COLLAPSE_RUNTIME_GOROUTINES)
+			    In Go crash outputs, collapse the goroutines whose frames are all in the runtime into a single
+			    '… N runtime goroutines' line.
+
+			  - '--merge' (ZAP_PRETTY_MERGE)
+			    Interleave the lines of the log files given as arguments by timestamp instead of printing them as they
+			    are read, like 'zap-pretty --merge api.json worker.json'. Lines that are not log lines, like a panic
+			    output, stay attached to the log line preceding them in their file. It cannot be used with '--go-test',
+			    '--dedupe' or '--summary'.
+
+			  - '--header-field' (ZAP_PRETTY_HEADER_FIELD)
+			    Promote a field into the header line, like '[ts] INFO (logger) [req=abc block=123] message', can be repeated.
+			    The format is 'key[=label][:color]' where key can be a dotted path, like 'request_id=req:yellow'.
+
+			  - '--header-format' (ZAP_PRETTY_HEADER_FORMAT)
+			    Go 'text/template' used to render the header of each line, like '{{.Level | pad 5}} {{.Time | fmt "15:04:05.000"}} {{.Logger}}: {{.Message}}'.
+			    Available data is .Time, .Timestamp, .Delta, .Relative, .Level, .Logger, .Caller, .Origin, .HeaderFields, .Message, .Source and .Stream
+			    and helpers are color, levelColor, pad, truncate, fmt, delta, upper and lower. The default renders
+			    '[time, relative] LEVEL (logger, caller) [header fields] message'.
+
+			  - '--time-format' (ZAP_PRETTY_TIME_FORMAT)
+			    Go time layout used to render timestamps, like '15:04:05.000', or one of the presets 'default', 'rfc3339',
+			    'rfc3339-ms', 'rfc3339-nano', 'datetime', 'time-only', 'kitchen', 'unix' or 'unix-ms'.
+
+			  - '--tz' (ZAP_PRETTY_TZ)
+			    Time zone used to render timestamps, either 'local', 'utc', an IANA name like 'America/Toronto' or 'original'
+			    to render them in the time zone the log was written in (numeric timestamps are rendered in local time).
 
 			  - '--show-delta, -d' (ZAP_PRETTY_SHOW_DELTA)
-			    On the timestamp field, add delta from the last seen log line, if any.
+			    On the timestamp field, add delta from the last seen log line, if any, same as '--time-mode=delta'.
+
+			  - '--time-mode' (ZAP_PRETTY_TIME_MODE)
+			    Relative time shown on the timestamp field, one of 'none', 'delta' (since previous line), 'elapsed'
+			    (since first line, like '+12.345s'), 'since-start' (since last line matching '--start-marker')
+			    or 'logger-delta' (since previous line of the same logger).
+
+			  - '--start-marker' (ZAP_PRETTY_START_MARKER)
+			    Regular expression matched against messages, each matching line resets the 'since-start' time mode reference.
+
+			  - '--slow-threshold' (ZAP_PRETTY_SLOW_THRESHOLD)
+			    Highlight gaps between consecutive lines longer than the threshold, like '500ms', by coloring the relative
+			    time and inserting a '──── 4.2s gap ────' separator. A second critical tier can be given, like '500ms,5s'.
+
+			  - '--out-of-order' (ZAP_PRETTY_OUT_OF_ORDER)
+			    Detect lines whose timestamp jumps back in time compared to the previous line, mark them with '↶ -1.2s'
+			    and print a summary count to stderr once input ends.
+
+			  - '--out-of-order-tolerance' (ZAP_PRETTY_OUT_OF_ORDER_TOLERANCE)
+			    Backwards time jumps smaller or equal to this duration are not reported by '--out-of-order'.
 
 			  - '--multiline-json-threshold, -n' (ZAP_PRETTY_MULTILINE_JSON_THRESHOLD)
 			    Format JSON as multiline if got more than n elements in data.
 
 			  - '--multiline-json-force, -m' (ZAP_PRETTY_MULTILINE_JSON_FORCE)
 			    Force JSON to be printed as multiline even if it's below the threshold, overrides and ignores 'multiline-json-threshold'.
+
+			  - '--max-field-length' (ZAP_PRETTY_MAX_FIELD_LENGTH)
+			    Elide string field values longer than this amount of bytes, like '0x1234…(+4012 bytes)', 0 means no limit.
+
+			  - '--max-array-items' (ZAP_PRETTY_MAX_ARRAY_ITEMS)
+			    Elide array field values after this amount of items, like '[1,2,"...12 more"]', 0 means no limit.
+
+			  - '--full-field' (ZAP_PRETTY_FULL_FIELD)
+			    Field key (name or dotted path like 'req.body') that is never truncated, can be repeated.
+
+			  - '--stack-depth' (ZAP_PRETTY_STACK_DEPTH)
+			    Render at most N frames of stack traces, a line folding library frames counting as one, the remaining
+			    ones are summarized in a single line, 0 means no limit.
 		`),
 
 		Flags(func(flags *pflag.FlagSet) {
 			flags.Bool("all", false, "Show all fields that would normally be ignored by default like 'serviceContext', 'labels', etc.")
+			flags.StringArray("hide-field", nil, "Hide fields whose dotted path matches the glob pattern, like 'req.headers.*', can be repeated")
+			flags.StringArray("only-field", nil, "Only show fields whose dotted path matches the glob pattern, like 'req.id', can be repeated")
+			flags.String("filter", "", "Only print log lines matching the expression, like 'logger == \"p2p\" && fields.peer_count < 3', see description for the syntax")
+			flags.IntP("after-context", "A", 0, "Print N log lines after each line matching '--filter'")
+			flags.IntP("before-context", "B", 0, "Print N log lines before each line matching '--filter'")
+			flags.IntP("context", "C", 0, "Print N log lines before and after each line matching '--filter'")
+			flags.StringArray("highlight", nil, "Mark the matches of the regular expression in the message and field values, optionally restricted to a field like 'req.path=~^/api', can be repeated")
+			flags.Bool("dedupe", false, "Collapse consecutive log lines with the same level, logger and message into a single one with a repetition count")
+			flags.Bool("dedupe-fields", false, "With '--dedupe', log lines must also have the same fields to be collapsed")
+			flags.StringArray("dedupe-ignore-field", nil, "With '--dedupe-fields', ignore fields whose dotted path matches the glob pattern, like 'attempt', can be repeated")
+			flags.Duration("dedupe-timeout", time.Second, "With '--dedupe', print the collapsed line after this duration even if repetitions continue, 0 waits for a different line")
+			flags.String("max-rate", "", "Print at most N log lines per level, logger and message each second, like '100/s', dropped lines are reported periodically")
+			flags.String("sample", "", "Only print one every N DEBUG log lines per level, logger and message, like '1/100', dropped lines are reported periodically")
+			flags.Bool("summary", false, "Print statistics about the input to stderr once it ends, on SIGUSR1 or when Enter is pressed")
+			flags.Int("summary-top", 10, "Amount of most frequent messages printed by '--summary'")
+			flags.Bool("status-bar", false, "When the output is a terminal, show a status bar at its bottom with lines/s, errors and warnings counts, last error time and filters in effect")
+			flags.String("tui", "", "Browse the given log file interactively with expandable records, incremental search, level toggles and follow mode")
+			flags.String("source", "", "Tag each line read from stdin with the source name, rendered in a color derived from the name, available to '--filter' as 'source'")
+			flags.String("prefix", "", "Split the prefix of each line off and render it as the source tag, one of 'compose', 'kubectl', 'stern' or a regular expression with a 'source' named group")
+			flags.Bool("show-stream", false, "Show the stream, like 'stderr', of the lines unwrapped from Docker json-file or CRI container logs")
+			flags.Bool("go-test", false, "Read the output of 'go test -json', prettifying the output of the tests grouped under their run, pass, fail and skip events")
+			flags.Bool("collapse-runtime-goroutines", false, "In Go crash outputs, collapse the goroutines whose frames are all in the runtime into a single line")
+			flags.Bool("merge", false, "Interleave the lines of the log files given as arguments by timestamp instead of printing them as they are read")
+			flags.StringArray("header-field", nil, "Promote a field into the header line, format is 'key[=label][:color]', like 'request_id=req:yellow', can be repeated")
+			flags.String("header-format", "", "Go 'text/template' used to render the header of each line, see description for available data and helpers")
+			flags.String("time-format", "default", "Go time layout used to render timestamps or one of the presets 'default', 'rfc3339', 'rfc3339-ms', 'rfc3339-nano', 'datetime', 'time-only', 'kitchen', 'unix' or 'unix-ms'")
+			flags.String("tz", "local", "Time zone used to render timestamps, either 'local', 'utc', an IANA name like 'America/Toronto' or 'original' for the time zone the log was written in")
 			flags.BoolP("show-delta", "d", false, "On the timestamp field, add delta from the last seen log line, if any")
+			flags.String("time-mode", "none", "Relative time shown on the timestamp field, one of 'none', 'delta', 'elapsed', 'since-start' or 'logger-delta'")
+			flags.String("start-marker", "", "Regular expression matched against messages, each matching line resets the 'since-start' time mode reference")
+			flags.StringSlice("slow-threshold", nil, "Highlight gaps between consecutive lines longer than the threshold, like '500ms', a second critical tier can be given, like '500ms,5s'")
+			flags.Bool("out-of-order", false, "Detect lines whose timestamp jumps back in time compared to the previous line, mark them and print a summary count to stderr once input ends")
+			flags.Duration("out-of-order-tolerance", 0, "Backwards time jumps smaller or equal to this duration are not reported by '--out-of-order'")
 			flags.IntP("multiline-json-threshold", "n", 3, "Format JSON as multiline if got more than n elements in data")
 			flags.BoolP("multiline-json-force", "m", false, "Force JSON to be printed as multiline even if it's below the threshold, overrides and ignores 'multiline-json-threshold'")
+			flags.Int("max-field-length", 0, "Elide string field values longer than this amount of bytes, 0 means no limit")
+			flags.Int("max-array-items", 0, "Elide array field values after this amount of items, 0 means no limit")
+			flags.StringArray("full-field", nil, "Field key (name or dotted path) that is never truncated, can be repeated")
+			flags.Int("stack-depth", 0, "Render at most N frames of stack traces, a line folding library frames counting as one, 0 means no limit")
 		}),
 
 		Example(`
@@ -85,6 +271,12 @@ func main() {
 			[2024-12-18 09:27:49.160 EST, +0] INFO (acme) checking if block available
 			[2024-12-18 09:28:39.160 EST, +40s] INFO (acme) optimistically fetching block {"block_num":308267722}
 			...
+
+			# Show elapsed time since the last "starting" line
+			go run ./cmd/acme | zap-pretty --time-mode=since-start --start-marker='^starting'
+			[2024-12-18 09:27:49.160 EST, +0s] INFO (acme) starting
+			[2024-12-18 09:27:51.505 EST, +2.345s] INFO (acme) ready
+			...
 		`),
 
 		Execute(run),
@@ -102,6 +294,26 @@ func run(cmd *cobra.Command, args []string) error {
 
 	go zapp.NewSignaler(debugEnabled, debugLogger).ForwardAllSignalsToProcessGroup()
 
+	if sflags.MustGetBool(cmd, "merge") && len(args) == 0 {
+		return fmt.Errorf("invalid flag 'merge': at least one log file must be given as argument")
+	}
+
+	if sflags.MustGetBool(cmd, "merge") {
+		for _, flag := range []string{"go-test", "dedupe", "summary"} {
+			if sflags.MustGetBool(cmd, flag) {
+				return fmt.Errorf("invalid flag 'merge': it cannot be used with '--%s'", flag)
+			}
+		}
+	}
+
+	if sflags.MustGetString(cmd, "source") != "" && len(args) > 0 {
+		return fmt.Errorf("invalid flag 'source': it only applies to stdin, name the log files given as arguments like 'api=api.json' instead")
+	}
+
+	if sflags.MustGetBool(cmd, "summary") && sflags.MustGetString(cmd, "tui") != "" {
+		return fmt.Errorf("invalid flag 'summary': it cannot be used with '--tui'")
+	}
+
 	// FIXME: How could we make it more resilient to we simply drop the line instead? Would that mean our own "scanner"?
 	// New scanner with a maximum of 250MiB per line, pass that, we panic.
 	scanner := bufio.NewScanner(os.Stdin)
@@ -111,21 +323,285 @@ func run(cmd *cobra.Command, args []string) error {
 		zapp.WithMultilineJSONFieldThreshold(sflags.MustGetInt(cmd, "multiline-json-threshold")),
 		zapp.WithMultilineJSONForced(sflags.MustGetBool(cmd, "multiline-json-force")),
 		zapp.WithDelta(sflags.MustGetBool(cmd, "show-delta")),
+		zapp.WithTimeFormat(sflags.MustGetString(cmd, "time-format")),
+		zapp.WithMaxFieldLength(sflags.MustGetInt(cmd, "max-field-length")),
+		zapp.WithMaxArrayItems(sflags.MustGetInt(cmd, "max-array-items")),
+		zapp.WithFullFields(sflags.MustGetStringArray(cmd, "full-field")...),
+		zapp.WithStackDepth(sflags.MustGetInt(cmd, "stack-depth")),
+		zapp.WithHiddenFields(sflags.MustGetStringArray(cmd, "hide-field")...),
+		zapp.WithOnlyFields(sflags.MustGetStringArray(cmd, "only-field")...),
 	}
 
 	if os.Getenv("ZAP_PRETTY_DEBUG") != "" {
 		opts = append(opts, zapp.WithDebugLogger(log.New(os.Stderr, "[pretty-debug] ", 0)))
 	}
 
+	if name := sflags.MustGetString(cmd, "time-mode"); name != "none" {
+		mode, err := zapp.ParseTimeMode(name)
+		if err != nil {
+			return fmt.Errorf("invalid flag 'time-mode': %w", err)
+		}
+
+		opts = append(opts, zapp.WithTimeMode(mode))
+	}
+
+	if expression := sflags.MustGetString(cmd, "filter"); expression != "" {
+		filter, err := zapp.ParseFilter(expression)
+		if err != nil {
+			return fmt.Errorf("invalid flag 'filter': %w", err)
+		}
+
+		opts = append(opts, zapp.WithFilter(filter))
+	}
+
+	for _, spec := range sflags.MustGetStringArray(cmd, "highlight") {
+		highlight, err := zapp.ParseHighlight(spec)
+		if err != nil {
+			return fmt.Errorf("invalid flag 'highlight': %w", err)
+		}
+
+		opts = append(opts, zapp.WithHighlights(highlight))
+	}
+
+	if sflags.MustGetBool(cmd, "dedupe") {
+		opts = append(opts,
+			zapp.WithDedupe(sflags.MustGetBool(cmd, "dedupe-fields"), sflags.MustGetStringArray(cmd, "dedupe-ignore-field")...),
+			zapp.WithDedupeTimeout(sflags.MustGetDuration(cmd, "dedupe-timeout")),
+		)
+	}
+
+	if value := sflags.MustGetString(cmd, "max-rate"); value != "" {
+		count, tick, err := zapp.ParseMaxRate(value)
+		if err != nil {
+			return fmt.Errorf("invalid flag 'max-rate': %w", err)
+		}
+
+		opts = append(opts, zapp.WithMaxRate(count, tick))
+	}
+
+	if value := sflags.MustGetString(cmd, "sample"); value != "" {
+		n, err := zapp.ParseSampling(value)
+		if err != nil {
+			return fmt.Errorf("invalid flag 'sample': %w", err)
+		}
+
+		opts = append(opts, zapp.WithDebugSampling(n))
+	}
+
+	if sflags.MustGetBool(cmd, "summary") {
+		requests := make(chan struct{}, 1)
+		zapp.NotifySummaryRequests(requests)
+
+		opts = append(opts,
+			zapp.WithSummary(sflags.MustGetInt(cmd, "summary-top")),
+			zapp.WithSummaryRequests(requests),
+		)
+	}
+
+	before, after := sflags.MustGetInt(cmd, "context"), sflags.MustGetInt(cmd, "context")
+	if value, provided := sflags.MustGetIntProvided(cmd, "before-context"); provided {
+		before = value
+	}
+	if value, provided := sflags.MustGetIntProvided(cmd, "after-context"); provided {
+		after = value
+	}
+	if before > 0 || after > 0 {
+		opts = append(opts, zapp.WithContext(before, after))
+	}
+
+	if expr := sflags.MustGetString(cmd, "start-marker"); expr != "" {
+		marker, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("invalid flag 'start-marker': %w", err)
+		}
+
+		opts = append(opts, zapp.WithStartMarker(marker))
+	}
+
+	if values := sflags.MustGetStringSlice(cmd, "slow-threshold"); len(values) > 0 {
+		if len(values) > 2 {
+			return fmt.Errorf("invalid flag 'slow-threshold': at most 2 thresholds can be given, got %d", len(values))
+		}
+
+		thresholds := make([]time.Duration, len(values))
+		for i, value := range values {
+			threshold, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("invalid flag 'slow-threshold': %w", err)
+			}
+
+			thresholds[i] = threshold
+		}
+
+		opts = append(opts, zapp.WithSlowThresholds(thresholds...))
+	}
+
+	if sflags.MustGetBool(cmd, "out-of-order") {
+		opts = append(opts, zapp.WithOutOfOrderDetection(sflags.MustGetDuration(cmd, "out-of-order-tolerance")))
+	}
+
+	if tz := sflags.MustGetString(cmd, "tz"); strings.EqualFold(tz, "original") {
+		opts = append(opts, zapp.WithOriginalTimeZone())
+	} else {
+		location, err := zapp.LoadTimeZone(tz)
+		if err != nil {
+			return fmt.Errorf("invalid flag 'tz': %w", err)
+		}
+
+		opts = append(opts, zapp.WithTimeZone(location))
+	}
+
+	for _, spec := range sflags.MustGetStringArray(cmd, "header-field") {
+		field, err := zapp.ParseHeaderField(spec)
+		if err != nil {
+			return fmt.Errorf("invalid flag 'header-field': %w", err)
+		}
+
+		opts = append(opts, zapp.WithHeaderFields(field))
+	}
+
+	if format := sflags.MustGetString(cmd, "header-format"); format != "" {
+		headerTemplate, err := zapp.NewHeaderTemplate(format)
+		if err != nil {
+			return fmt.Errorf("invalid flag 'header-format': %w", err)
+		}
+
+		opts = append(opts, zapp.WithHeaderTemplate(headerTemplate))
+	}
+
+	if value := sflags.MustGetString(cmd, "prefix"); value != "" {
+		prefix, err := zapp.ParseLinePrefix(value)
+		if err != nil {
+			return fmt.Errorf("invalid flag 'prefix': %w", err)
+		}
+
+		opts = append(opts, zapp.WithLinePrefix(prefix))
+	}
+
+	if sflags.MustGetBool(cmd, "show-stream") {
+		opts = append(opts, zapp.WithStreamName())
+	}
+
+	if sflags.MustGetBool(cmd, "go-test") {
+		opts = append(opts, zapp.WithGoTestJSON())
+	}
+
+	if sflags.MustGetBool(cmd, "collapse-runtime-goroutines") {
+		opts = append(opts, zapp.WithRuntimeGoroutinesCollapsed())
+	}
+
 	if sflags.MustGetBool(cmd, "all") {
 		opts = append(opts, zapp.WithAllFields())
 	}
 
-	zapp.NewProcessor(scanner, os.Stdout, opts...).Process()
+	if path := sflags.MustGetString(cmd, "tui"); path != "" {
+		return zapp.NewPager(opts...).Run(path)
+	}
+
+	var output io.Writer = os.Stdout
+	if sflags.MustGetBool(cmd, "status-bar") && zapp.IsTerminal(os.Stdout) {
+		bar := zapp.NewStatusBar(os.Stdout, statusBarFilters(cmd)...)
+		output = bar.Wrap(os.Stdout)
+		opts = append(opts, zapp.WithStatusBar(bar), zapp.WithSummaryOutput(bar.Wrap(os.Stderr)))
+
+		bar.Start(time.Second)
+		defer bar.Stop()
+	}
+
+	if len(args) > 0 {
+		sources, closeSources, err := inputSources(args)
+		if err != nil {
+			return err
+		}
+		defer closeSources()
+
+		if sflags.MustGetBool(cmd, "merge") {
+			zapp.NewProcessor(nil, output, opts...).Merge(sources...)
+		} else {
+			zapp.NewProcessor(nil, output, opts...).Stream(sources...)
+		}
+
+		return nil
+	}
+
+	if name := sflags.MustGetString(cmd, "source"); name != "" {
+		opts = append(opts, zapp.WithSource(name))
+	}
+
+	zapp.NewProcessor(scanner, output, opts...).Process()
 
 	return nil
 }
 
+var sourceNameRegex = regexp.MustCompile(`^[\w.-]+$`)
+
+// inputSources opens the log files given as arguments, like 'api.json' or 'api=<(run api)'.
+// A source is named after the file base name without extension unless a name is given, names
+// that collide are suffixed by their position. The returned function closes the files.
+func inputSources(args []string) ([]zapp.Source, func(), error) {
+	names := make([]string, len(args))
+	paths := make([]string, len(args))
+	counts := map[string]int{}
+
+	for i, arg := range args {
+		if name, path, found := strings.Cut(arg, "="); found && sourceNameRegex.MatchString(name) {
+			names[i], paths[i] = name, path
+		} else {
+			names[i], paths[i] = strings.TrimSuffix(filepath.Base(arg), filepath.Ext(arg)), arg
+		}
+
+		counts[names[i]]++
+	}
+
+	var files []*os.File
+	closeFiles := func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}
+
+	sources := make([]zapp.Source, len(args))
+	for i, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			closeFiles()
+			return nil, nil, err
+		}
+		files = append(files, file)
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(nil, 250*1024*1024)
+
+		name := names[i]
+		if counts[name] > 1 {
+			name = fmt.Sprintf("%s-%d", name, i+1)
+		}
+
+		sources[i] = zapp.Source{Name: name, Scanner: scanner}
+	}
+
+	return sources, closeFiles, nil
+}
+
+// statusBarFilters describes the flags affecting which lines are printed for the status bar.
+func statusBarFilters(cmd *cobra.Command) (filters []string) {
+	if expression := sflags.MustGetString(cmd, "filter"); expression != "" {
+		filters = append(filters, "filter: "+expression)
+	}
+
+	for _, flag := range []string{"max-rate", "sample"} {
+		if value := sflags.MustGetString(cmd, flag); value != "" {
+			filters = append(filters, flag+": "+value)
+		}
+	}
+
+	if sflags.MustGetBool(cmd, "dedupe") {
+		filters = append(filters, "dedupe")
+	}
+
+	return filters
+}
+
 func OnCommandErrorPrintAndExit() CommandOption {
 	if OnAssertionFailure == nil {
 		OnAssertionFailure = func(message string) {
@@ -0,0 +1,52 @@
+package zapp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	. "github.com/logrusorgru/aurora"
+)
+
+var colorsByName = map[string]Color{
+	"black":          BlackFg,
+	"red":            RedFg,
+	"green":          GreenFg,
+	"yellow":         YellowFg,
+	"blue":           BlueFg,
+	"magenta":        MagentaFg,
+	"cyan":           CyanFg,
+	"white":          WhiteFg,
+	"gray":           Gray(12, "").Color(),
+	"bright-red":     BrightFg | RedFg,
+	"bright-green":   BrightFg | GreenFg,
+	"bright-yellow":  BrightFg | YellowFg,
+	"bright-blue":    BrightFg | BlueFg,
+	"bright-magenta": BrightFg | MagentaFg,
+	"bright-cyan":    BrightFg | CyanFg,
+	"bright-white":   BrightFg | WhiteFg,
+	"bold":           BoldFm,
+	"faint":          FaintFm,
+	"inverse":        InverseFm,
+}
+
+// ParseColor returns the color matching `name`, see `ColorNames` for accepted values.
+func ParseColor(name string) (Color, error) {
+	color, found := colorsByName[strings.ToLower(name)]
+	if !found {
+		return 0, fmt.Errorf("unknown color %q, valid values are %s", name, strings.Join(ColorNames(), ", "))
+	}
+
+	return color, nil
+}
+
+// ColorNames returns the sorted list of color names accepted by `ParseColor`.
+func ColorNames() []string {
+	names := make([]string, 0, len(colorsByName))
+	for name := range colorsByName {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,122 @@
+package zapp
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+
+	. "github.com/logrusorgru/aurora"
+)
+
+// criLineRegex matches the lines of the CRI log format written by containerd and CRI-O,
+// like `2024-01-02T03:04:05.123456789Z stderr F {...}`, where the tag is `P` for a partial
+// line continued by the next one and `F` for the last part of a line.
+var criLineRegex = regexp.MustCompile(`^\d{4}-\d\d-\d\dT\S+ (stdout|stderr) ([PF]) (.*)$`)
+
+// containerPartialKey identifies the line being reassembled, the lines of each stream of a
+// source are split independently so that their parts can be interleaved.
+type containerPartialKey struct {
+	source string
+	stream string
+}
+
+// containerPartial is the beginning of a line split by the container runtime, kept until
+// its last part arrives.
+type containerPartial struct {
+	text strings.Builder
+}
+
+// WithStreamName shows the stream of the lines unwrapped from a Docker json-file or CRI log
+// envelope, like `stderr`, right before the header.
+func WithStreamName() ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.showStream = true
+	})
+}
+
+// unwrapContainerLine returns the line wrapped in a Docker json-file envelope, like
+// `{"log":"...\n","stream":"stderr","time":"..."}`, or in a CRI one along with its stream.
+// Lines split by the container runtime are reassembled, false is returned for the parts
+// preceding the last one. Other lines are returned as is.
+func (p *Processor) unwrapContainerLine(source string, line string) (string, string, bool) {
+	var text, stream string
+	var partial bool
+
+	if strings.HasPrefix(line, `{"log":`) {
+		var envelope struct {
+			Log    *string `json:"log"`
+			Stream string  `json:"stream"`
+		}
+
+		if err := json.Unmarshal([]byte(line), &envelope); err != nil || envelope.Log == nil {
+			return line, "", true
+		}
+
+		// Docker splits lines longer than 16KiB, only the last part ends with a newline
+		text = strings.TrimSuffix(*envelope.Log, "\n")
+		partial = len(text) == len(*envelope.Log)
+		text = strings.TrimSuffix(text, "\r")
+		stream = envelope.Stream
+	} else if match := criLineRegex.FindStringSubmatch(line); match != nil {
+		stream, partial, text = match[1], match[2] == "P", match[3]
+	} else {
+		return line, "", true
+	}
+
+	key := containerPartialKey{source: source, stream: stream}
+	pending := p.containerPartials[key]
+	if partial {
+		if pending == nil {
+			if p.containerPartials == nil {
+				p.containerPartials = map[containerPartialKey]*containerPartial{}
+			}
+
+			pending = &containerPartial{}
+			p.containerPartials[key] = pending
+		}
+
+		pending.text.WriteString(text)
+		return "", "", false
+	}
+
+	if pending != nil {
+		delete(p.containerPartials, key)
+		pending.text.WriteString(text)
+		text = pending.text.String()
+	}
+
+	return text, stream, true
+}
+
+// flushContainerPartials processes the lines whose last part never arrived once the input
+// ends.
+func (p *Processor) flushContainerPartials() {
+	keys := make([]containerPartialKey, 0, len(p.containerPartials))
+	for key := range p.containerPartials {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].source != keys[j].source {
+			return keys[i].source < keys[j].source
+		}
+
+		return keys[i].stream < keys[j].stream
+	})
+
+	for _, key := range keys {
+		pending := p.containerPartials[key]
+		delete(p.containerPartials, key)
+
+		p.processUnwrappedLine(key.source, key.stream, pending.text.String())
+	}
+}
+
+// streamTagged prefixes a line that is not a log line with its stream when it's shown.
+func (p *Processor) streamTagged(stream string, line string) string {
+	if !p.showStream || stream == "" {
+		return line
+	}
+
+	return Gray(12, stream).String() + " " + line
+}
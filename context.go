@@ -0,0 +1,117 @@
+package zapp
+
+import (
+	. "github.com/logrusorgru/aurora"
+)
+
+// contextEntry is a log line kept around to be printed as context of a matching line.
+type contextEntry struct {
+	line string
+	rec  *record
+	seq  int
+}
+
+// contextRing is a fixed capacity ring buffer of the most recent non-matching log lines
+// used to print the lines before a matching one.
+type contextRing struct {
+	entries []contextEntry
+	start   int
+	count   int
+}
+
+func newContextRing(capacity int) *contextRing {
+	return &contextRing{entries: make([]contextEntry, capacity)}
+}
+
+func (r *contextRing) push(entry contextEntry) {
+	if len(r.entries) == 0 {
+		return
+	}
+
+	if r.count < len(r.entries) {
+		r.entries[(r.start+r.count)%len(r.entries)] = entry
+		r.count++
+		return
+	}
+
+	// Full, overwrite the oldest entry
+	r.entries[r.start] = entry
+	r.start = (r.start + 1) % len(r.entries)
+}
+
+// drain calls `fn` on each entry from the oldest to the most recent and empties the ring.
+func (r *contextRing) drain(fn func(entry contextEntry)) {
+	for i := 0; i < r.count; i++ {
+		index := (r.start + i) % len(r.entries)
+		fn(r.entries[index])
+		r.entries[index] = contextEntry{}
+	}
+
+	r.start, r.count = 0, 0
+}
+
+// handleRecord decides if the record is printed according to the filter and the context
+// configuration, non-matching records are either printed as context after a match, kept
+// in the ring buffer as context before a future match or dropped.
+func (p *Processor) handleRecord(line string, rec *record) {
+	seq := p.recordSeq
+	p.recordSeq++
+
+	if p.filter == nil || p.filter.match(rec) {
+		if p.contextRing != nil {
+			p.contextRing.drain(func(entry contextEntry) {
+				p.printRecord(entry.line, entry.rec, entry.seq)
+			})
+		}
+
+		p.printRecord(line, rec, seq)
+		p.contextAfterRemaining = p.contextAfter
+		return
+	}
+
+	if p.contextAfterRemaining > 0 {
+		p.contextAfterRemaining--
+		p.printRecord(line, rec, seq)
+		return
+	}
+
+	if p.contextRing != nil {
+		p.contextRing.push(contextEntry{line: line, rec: rec, seq: seq})
+		return
+	}
+
+	p.debugPrintln("Line filtered out")
+}
+
+// printRecord prints the record, unless it's dropped by sampling or held back to collapse
+// repetitions.
+func (p *Processor) printRecord(line string, rec *record, seq int) {
+	if !p.sampleRecord(rec) {
+		p.debugPrintln("Line dropped by sampling")
+		return
+	}
+
+	if p.dedupeRecord(line, rec, seq) {
+		return
+	}
+
+	p.writeRecord(line, rec, seq, seq, "")
+}
+
+// writeRecord renders and writes the record followed by `suffix`, when context is enabled
+// a `--` separator is written first if the record doesn't directly follow the last printed
+// one. The `lastSeq` is the one of the last record collapsed into this one, if any.
+func (p *Processor) writeRecord(line string, rec *record, seq int, lastSeq int, suffix string) {
+	if (p.contextBefore > 0 || p.contextAfter > 0) && p.lastPrintedSeq != nil && seq != *p.lastPrintedSeq+1 {
+		p.writeLine(Gray(12, "--").String())
+	}
+	p.lastPrintedSeq = &lastSeq
+
+	prettyLine, err := p.renderRecord(rec)
+	if err != nil {
+		p.notPrettyPrintedLine(rec.source, p.streamTagged(rec.stream, line), err)
+		return
+	}
+
+	p.writeLine(prettyLine + suffix)
+}
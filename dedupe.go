@@ -0,0 +1,121 @@
+package zapp
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	. "github.com/logrusorgru/aurora"
+)
+
+// dedupeRun is a record held back while the following records are identical to it, it's
+// printed once with the amount of repetitions when the run ends.
+type dedupeRun struct {
+	line    string
+	rec     *record
+	seq     int
+	fields  map[string]interface{}
+	count   int
+	lastRec *record
+	lastSeq int
+	heldAt  time.Time
+}
+
+// dedupeRecord holds the record back if deduplication is enabled, it's only counted if it
+// repeats the held record, otherwise the held record is flushed and the new one takes its
+// place. It returns false when deduplication is disabled.
+func (p *Processor) dedupeRecord(line string, rec *record, seq int) bool {
+	if !p.dedupe {
+		return false
+	}
+
+	var fields map[string]interface{}
+	if p.dedupeFields {
+		fields = p.dedupeComparedFields(rec)
+	}
+
+	if run := p.dedupeRun; run != nil && p.isDuplicate(run, rec, fields) {
+		run.count++
+		run.lastRec = rec
+		run.lastSeq = seq
+		return true
+	}
+
+	p.flushDedupe()
+	p.dedupeRun = &dedupeRun{line: line, rec: rec, seq: seq, fields: fields, count: 1, lastRec: rec, lastSeq: seq, heldAt: time.Now()}
+	return true
+}
+
+func (p *Processor) isDuplicate(run *dedupeRun, rec *record, fields map[string]interface{}) bool {
+	if run.rec.severity != rec.severity || run.rec.message != rec.message || run.rec.source != rec.source {
+		return false
+	}
+
+	if (run.rec.logger == nil) != (rec.logger == nil) || (rec.logger != nil && *run.rec.logger != *rec.logger) {
+		return false
+	}
+
+	return !p.dedupeFields || reflect.DeepEqual(run.fields, fields)
+}
+
+// dedupeComparedFields returns the fields of the record compared to find duplicates, that
+// is all of them except the ones matching the ignored patterns.
+func (p *Processor) dedupeComparedFields(rec *record) map[string]interface{} {
+	fields := rec.fields
+	if len(p.dedupeIgnoredFields) > 0 {
+		fields = p.selectObjectFields("", fields, p.dedupeIgnoredFields, true)
+	}
+
+	if !rec.hasErrorDetails() {
+		return fields
+	}
+
+	fields = copyObject(fields)
+	fields["errorVerbose"] = rec.errorVerboses
+	fields["stacktrace"] = rec.stacktrace
+	fields["errorCauses"] = rec.errorCauses
+
+	return fields
+}
+
+// flushDedupe prints the held record if any, followed by the amount of times it was seen
+// when it was repeated.
+func (p *Processor) flushDedupe() {
+	run := p.dedupeRun
+	if run == nil {
+		return
+	}
+	p.dedupeRun = nil
+
+	suffix := ""
+	if run.count > 1 {
+		suffix = " " + Gray(12, dedupeSummary(run)).String()
+	}
+
+	p.writeRecord(run.line, run.rec, run.seq, run.lastSeq, suffix)
+
+	// The relative time of the next line is computed from the last repetition, unless it
+	// jumped back in time
+	last := run.lastRec.timestamp
+	if run.count > 1 && (last == nil || p.lastProcessedTimestamp == nil || !last.Before(*p.lastProcessedTimestamp)) {
+		p.trackTimestamp(run.lastRec)
+	}
+}
+
+// dedupeFlushDeadline returns the time at which the held record must be flushed, false if
+// there is none.
+func (p *Processor) dedupeFlushDeadline() (time.Time, bool) {
+	if p.dedupeRun == nil || p.dedupeTimeout <= 0 {
+		return time.Time{}, false
+	}
+
+	return p.dedupeRun.heldAt.Add(p.dedupeTimeout), true
+}
+
+func dedupeSummary(run *dedupeRun) string {
+	if run.rec.timestamp == nil || run.lastRec.timestamp == nil {
+		return fmt.Sprintf("(repeated %d times)", run.count)
+	}
+
+	return fmt.Sprintf("(repeated %d times over %s)", run.count, durationToString(run.lastRec.timestamp.Sub(*run.rec.timestamp)))
+}
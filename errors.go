@@ -0,0 +1,222 @@
+package zapp
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// errorVerbose is the verbose form of an error field, like the `errorVerbose` zap logs along
+// with `error` for errors that implement `fmt.Formatter`, or `causeVerbose` for the error
+// logged with `zap.NamedError("cause", err)`.
+type errorVerbose struct {
+	key  string
+	text string
+}
+
+// extractErrorVerboses removes the verbose forms of the error fields from the fields and
+// returns them, the one of the `error` field first. A `<key>Verbose` field is the verbose
+// form of an error only if the `<key>` field is a string too, except for `errorVerbose`.
+func extractErrorVerboses(lineData map[string]interface{}) []errorVerbose {
+	var verboses []errorVerbose
+	for name, value := range lineData {
+		text, ok := value.(string)
+		if !ok || text == "" || !strings.HasSuffix(name, "Verbose") {
+			continue
+		}
+
+		key := strings.TrimSuffix(name, "Verbose")
+		if _, isString := lineData[key].(string); key == "" || (key != "error" && !isString) {
+			continue
+		}
+
+		verboses = append(verboses, errorVerbose{key: key, text: text})
+	}
+
+	sort.Slice(verboses, func(i, j int) bool {
+		if (verboses[i].key == "error") != (verboses[j].key == "error") {
+			return verboses[i].key == "error"
+		}
+
+		return verboses[i].key < verboses[j].key
+	})
+
+	for _, verbose := range verboses {
+		delete(lineData, verbose.key+"Verbose")
+	}
+
+	return verboses
+}
+
+// errorVerboseOf returns the verbose form of the error field, empty if there is none.
+func (rec *record) errorVerboseOf(key string) string {
+	for _, verbose := range rec.errorVerboses {
+		if verbose.key == key {
+			return verbose.text
+		}
+	}
+
+	return ""
+}
+
+// errorVerboseText returns the verbose forms of all the error fields of the record.
+func (rec *record) errorVerboseText() string {
+	texts := make([]string, len(rec.errorVerboses))
+	for i, verbose := range rec.errorVerboses {
+		texts[i] = verbose.text
+	}
+
+	return strings.Join(texts, "\n")
+}
+
+// errorCause is one of the errors combined into the logged error, like those of
+// `multierr.Combine` that zap logs in the `errorCauses` field along with `error`.
+type errorCause struct {
+	message string
+	verbose string
+	causes  []errorCause
+}
+
+// extractErrorCauses returns the causes of the logged error. Those are the `errorCauses`
+// array zap logs for errors combining several ones, removed from the fields, or the lines of
+// an `error` spanning multiple lines like the ones created by `errors.Join`, in which case
+// true is returned too. The `error` field is kept so that filters, search and dedupe can
+// still read it, see `renderedFields`.
+func extractErrorCauses(lineData map[string]interface{}) ([]errorCause, bool) {
+	if causes, ok := parseErrorCauses(lineData["errorCauses"]); ok {
+		delete(lineData, "errorCauses")
+		return causes, false
+	}
+
+	message, ok := lineData["error"].(string)
+	if !ok || !strings.Contains(strings.TrimSpace(message), "\n") {
+		return nil, false
+	}
+
+	var causes []errorCause
+	for _, line := range strings.Split(message, "\n") {
+		if strings.TrimSpace(line) != "" {
+			causes = append(causes, errorCause{message: line})
+		}
+	}
+
+	return causes, true
+}
+
+// renderedFields returns the fields of the record rendered as JSON, that is all of them
+// except an `error` already rendered line by line as the error causes.
+func (rec *record) renderedFields() map[string]interface{} {
+	if !rec.errorJoined {
+		return rec.fields
+	}
+
+	fields := make(map[string]interface{}, len(rec.fields))
+	for key, value := range rec.fields {
+		if key != "error" {
+			fields[key] = value
+		}
+	}
+
+	return fields
+}
+
+// parseErrorCauses parses an `errorCauses` array, each cause is an object with an `error`
+// message, an optional `errorVerbose` and its own `errorCauses` when it combines several
+// errors too. False is returned if the value is not in this format.
+func parseErrorCauses(value interface{}) ([]errorCause, bool) {
+	elements, ok := value.([]interface{})
+	if !ok || len(elements) == 0 {
+		return nil, false
+	}
+
+	causes := make([]errorCause, 0, len(elements))
+	for _, element := range elements {
+		object, ok := element.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		message, ok := object["error"].(string)
+		if !ok {
+			return nil, false
+		}
+
+		cause := errorCause{message: message}
+		cause.verbose, _ = object["errorVerbose"].(string)
+
+		if object["errorCauses"] != nil {
+			if cause.causes, ok = parseErrorCauses(object["errorCauses"]); !ok {
+				return nil, false
+			}
+		}
+
+		causes = append(causes, cause)
+	}
+
+	return causes, true
+}
+
+// writeErrorCauses writes the causes as an indented tree, the `errorVerbose` of a cause is
+// written below its message.
+func writeErrorCauses(buffer *bytes.Buffer, causes []errorCause) {
+	buffer.WriteByte('\n')
+	buffer.WriteString("Error Causes")
+	writeErrorCauseTree(buffer, causes, "  ")
+}
+
+func writeErrorCauseTree(buffer *bytes.Buffer, causes []errorCause, indent string) {
+	for _, cause := range causes {
+		lines := strings.Split(strings.TrimRight(cause.message, "\n"), "\n")
+
+		buffer.WriteString("\n" + indent + "- " + lines[0])
+		for _, line := range lines[1:] {
+			buffer.WriteString("\n" + indent + "  " + line)
+		}
+
+		if cause.verbose != "" {
+			var verbose bytes.Buffer
+			writeErrorVerboseBody(&verbose, cause.verbose)
+
+			buffer.WriteByte('\n')
+			buffer.WriteString(indentLines(dedentLines(strings.TrimLeft(verbose.String(), "\n")), indent+"  "))
+		}
+
+		writeErrorCauseTree(buffer, cause.causes, indent+"  ")
+	}
+}
+
+// indentLines prefixes the non-empty lines of the text with the indentation.
+func indentLines(text string, indent string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = indent + line
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// dedentLines removes the leading spaces common to all the non-empty lines of the text.
+func dedentLines(text string) string {
+	lines := strings.Split(text, "\n")
+
+	common := -1
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+
+		if spaces := len(line) - len(strings.TrimLeft(line, " ")); common < 0 || spaces < common {
+			common = spaces
+		}
+	}
+
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = line[common:]
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
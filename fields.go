@@ -0,0 +1,217 @@
+package zapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// zapdriverHiddenFields is the default hide profile applied to Zapdriver lines, it's
+// disabled when all fields are requested via `WithAllFields`.
+var zapdriverHiddenFields = []string{
+	"labels",
+	"serviceContext",
+	"logging.googleapis.com/*",
+}
+
+// selectFields returns a copy of data from which hidden fields have been removed and, if
+// some only fields patterns are configured, where only the fields matching them are kept.
+// The `profile` argument is the format specific default hide profile, ignored when all
+// fields are requested.
+//
+// Patterns are matched against the dotted path of the field (`req.headers.host`), see
+// `matchFieldPattern` for the glob syntax.
+func (p *Processor) selectFields(data map[string]interface{}, profile []string) map[string]interface{} {
+	hidden := p.hiddenFields
+	if !p.showAllFields && len(profile) > 0 {
+		hidden = append(append([]string{}, profile...), hidden...)
+	}
+
+	if len(hidden) == 0 && len(p.onlyFields) == 0 {
+		return data
+	}
+
+	return p.selectObjectFields("", data, hidden, len(p.onlyFields) == 0)
+}
+
+func (p *Processor) selectObjectFields(path string, data map[string]interface{}, hidden []string, selected bool) map[string]interface{} {
+	out := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		keyPath := joinFieldPath(path, key)
+		if matchAnyFieldPattern(hidden, keyPath) {
+			continue
+		}
+
+		keySelected := selected || matchAnyFieldPattern(p.onlyFields, keyPath)
+
+		object, isObject := value.(map[string]interface{})
+		if !isObject {
+			if keySelected {
+				out[key] = value
+			}
+
+			continue
+		}
+
+		if !keySelected && !matchAnyFieldPatternPrefix(p.onlyFields, keyPath+".") {
+			continue
+		}
+
+		child := p.selectObjectFields(keyPath, object, hidden, keySelected)
+		if !keySelected && len(child) == 0 {
+			continue
+		}
+
+		out[key] = child
+	}
+
+	return out
+}
+
+func joinFieldPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+func matchAnyFieldPattern(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if matchFieldPattern(pattern, path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchAnyFieldPatternPrefix(patterns []string, prefix string) bool {
+	for _, pattern := range patterns {
+		if globMatch(pattern, prefix, true) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchFieldPattern reports whether the dotted field `path` matches the glob `pattern`.
+// In the pattern, `*` matches any sequence of characters except `.`, `**` matches any
+// sequence of characters including `.` and `?` matches a single character except `.`.
+func matchFieldPattern(pattern string, path string) bool {
+	return globMatch(pattern, path, false)
+}
+
+// globMatch implements `matchFieldPattern`, when `partial` is true, it instead reports
+// whether some continuation of `name` could match the pattern.
+func globMatch(pattern string, name string, partial bool) bool {
+	for len(pattern) > 0 {
+		if strings.HasPrefix(pattern, "**") {
+			for i := 0; i <= len(name); i++ {
+				if globMatch(pattern[2:], name[i:], partial) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if pattern[0] == '*' {
+			for i := 0; i <= len(name); i++ {
+				if globMatch(pattern[1:], name[i:], partial) {
+					return true
+				}
+
+				if i < len(name) && name[i] == '.' {
+					return false
+				}
+			}
+
+			return false
+		}
+
+		if len(name) == 0 {
+			return partial
+		}
+
+		if pattern[0] == '?' {
+			if name[0] == '.' {
+				return false
+			}
+		} else if pattern[0] != name[0] {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// removeFieldPath removes and returns the value found at the dotted `path` in data, a key
+// containing dots is matched as-is before trying to descend into nested objects. Nested
+// objects along the path are copied so that they are never mutated and are removed when
+// they become empty.
+func removeFieldPath(data map[string]interface{}, path string) (interface{}, bool) {
+	if value, found := data[path]; found {
+		delete(data, path)
+		return value, true
+	}
+
+	head, rest, found := strings.Cut(path, ".")
+	if !found {
+		return nil, false
+	}
+
+	child, isObject := data[head].(map[string]interface{})
+	if !isObject {
+		return nil, false
+	}
+
+	child = copyObject(child)
+	value, found := removeFieldPath(child, rest)
+	if !found {
+		return nil, false
+	}
+
+	if len(child) == 0 {
+		delete(data, head)
+	} else {
+		data[head] = child
+	}
+
+	return value, true
+}
+
+func copyObject(data map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		out[key] = value
+	}
+
+	return out
+}
+
+// fieldValueToString renders a decoded JSON value for display, strings are printed
+// without quotes, numbers without exponent and everything else as compact JSON.
+func fieldValueToString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+
+	out, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+
+	return string(out)
+}
@@ -0,0 +1,685 @@
+package zapp
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Filter is a compiled filter expression evaluated against each parsed log line, see
+// `ParseFilter` for the syntax.
+type Filter struct {
+	expression string
+	root       filterNode
+}
+
+// ParseFilter compiles a filter expression like `logger == "p2p" && fields.peer_count < 3`.
+//
+// The following identifiers are available: `level` (lower-cased), `logger`, `caller`, `msg`
+// (or `message`), `ts` (unix seconds), `stacktrace`, `errorVerbose` (of the `error` field),
+// `source` (name of the input the line comes from), `stream` (stream of the container log
+// envelope) and `fields` which gives access to the extra fields of the line with
+// `fields.req.id` or `fields["weird-key"]`. Missing values are `null`.
+//
+// Literals are strings (`"..."` or `'...'`), numbers, `true`, `false` and `null`. Values are
+// compared with `==`, `!=`, `<`, `<=`, `>`, `>=` which are typed (comparing values of different
+// types is never true except for `!=`), levels compare by severity when both sides are known
+// levels (`level >= "warn"`). Strings are matched against a regular expression literal with
+// `=~` and `!~`. The function `exists(path)` reports if a value is present. Expressions are
+// combined with `&&`, `||`, `!` and parentheses.
+func ParseFilter(expression string) (*Filter, error) {
+	parser := &filterParser{lexer: &filterLexer{input: expression}}
+	if err := parser.next(); err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", expression, err)
+	}
+
+	root, err := parser.parseOr()
+	if err == nil && parser.token.kind != filterTokenEOF {
+		err = fmt.Errorf("unexpected %s at position %d", parser.token, parser.token.position)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", expression, err)
+	}
+
+	return &Filter{expression: expression, root: root}, nil
+}
+
+// MustParseFilter is like `ParseFilter` but panics on error.
+func MustParseFilter(expression string) *Filter {
+	filter, err := ParseFilter(expression)
+	if err != nil {
+		panic(err)
+	}
+
+	return filter
+}
+
+func (f *Filter) String() string {
+	return f.expression
+}
+
+// match reports whether the record satisfies the filter expression.
+func (f *Filter) match(rec *record) bool {
+	return isTruthy(f.root.eval(rec))
+}
+
+type filterNode interface {
+	eval(rec *record) interface{}
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(rec *record) interface{} {
+	return n.value
+}
+
+type pathNode struct {
+	// root is the first element of the path, like `level` or `fields`
+	root string
+	path []string
+}
+
+func (n *pathNode) eval(rec *record) interface{} {
+	switch n.root {
+	case "level":
+		return strings.ToLower(rec.severity)
+	case "logger":
+		return optionalStringValue(rec.logger)
+	case "caller":
+		return optionalStringValue(rec.caller)
+	case "msg", "message":
+		return rec.message
+	case "ts":
+		if rec.timestamp == nil {
+			return nil
+		}
+
+		return float64(rec.timestamp.UnixNano()) / 1e9
+	case "stacktrace":
+		return emptyStringAsNull(rec.stacktrace)
+	case "errorVerbose":
+		return emptyStringAsNull(rec.errorVerboseOf("error"))
+	case "source":
+		return emptyStringAsNull(rec.source)
+	case "stream":
+		return emptyStringAsNull(rec.stream)
+	}
+
+	var value interface{} = rec.fields
+	for _, key := range n.path {
+		object, isObject := value.(map[string]interface{})
+		if !isObject {
+			return nil
+		}
+
+		if value = object[key]; value == nil {
+			return nil
+		}
+	}
+
+	return value
+}
+
+func optionalStringValue(value *string) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	return *value
+}
+
+func emptyStringAsNull(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+
+	return value
+}
+
+type existsNode struct {
+	path *pathNode
+}
+
+func (n *existsNode) eval(rec *record) interface{} {
+	return n.path.eval(rec) != nil
+}
+
+type notNode struct {
+	operand filterNode
+}
+
+func (n *notNode) eval(rec *record) interface{} {
+	return !isTruthy(n.operand.eval(rec))
+}
+
+type logicalNode struct {
+	and         bool
+	left, right filterNode
+}
+
+func (n *logicalNode) eval(rec *record) interface{} {
+	left := isTruthy(n.left.eval(rec))
+	if n.and {
+		return left && isTruthy(n.right.eval(rec))
+	}
+
+	return left || isTruthy(n.right.eval(rec))
+}
+
+type matchNode struct {
+	negate  bool
+	operand filterNode
+	regex   *regexp.Regexp
+}
+
+func (n *matchNode) eval(rec *record) interface{} {
+	value := n.operand.eval(rec)
+	if value == nil {
+		return n.negate
+	}
+
+	return n.regex.MatchString(fieldValueToString(value)) != n.negate
+}
+
+type compareNode struct {
+	operator    string
+	left, right filterNode
+}
+
+func (n *compareNode) eval(rec *record) interface{} {
+	left, right := n.left.eval(rec), n.right.eval(rec)
+
+	switch n.operator {
+	case "==":
+		return valuesEqual(left, right)
+	case "!=":
+		return !valuesEqual(left, right)
+	}
+
+	order, comparable := compareValues(left, right)
+	if !comparable {
+		return false
+	}
+
+	switch n.operator {
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	case ">=":
+		return order >= 0
+	}
+
+	return false
+}
+
+func valuesEqual(left, right interface{}) bool {
+	switch l := left.(type) {
+	case nil:
+		return right == nil
+	case string:
+		r, ok := right.(string)
+		return ok && l == r
+	case float64:
+		r, ok := right.(float64)
+		return ok && l == r
+	case bool:
+		r, ok := right.(bool)
+		return ok && l == r
+	}
+
+	// Objects and arrays are compared through their JSON representation
+	return right != nil && fieldValueToString(left) == fieldValueToString(right)
+}
+
+var levelsRank = map[string]int{
+	"debug":     0,
+	"info":      1,
+	"warn":      2,
+	"warning":   2,
+	"error":     3,
+	"dpanic":    4,
+	"critical":  4,
+	"panic":     5,
+	"alert":     5,
+	"fatal":     6,
+	"emergency": 6,
+}
+
+// compareValues orders two values of the same type, numbers and strings are supported,
+// strings that are both known levels are ordered by severity.
+func compareValues(left, right interface{}) (int, bool) {
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return 0, false
+		}
+
+		switch {
+		case l < r:
+			return -1, true
+		case l > r:
+			return 1, true
+		}
+
+		return 0, true
+
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return 0, false
+		}
+
+		leftRank, leftIsLevel := levelsRank[strings.ToLower(l)]
+		rightRank, rightIsLevel := levelsRank[strings.ToLower(r)]
+		if leftIsLevel && rightIsLevel {
+			return leftRank - rightRank, true
+		}
+
+		return strings.Compare(l, r), true
+	}
+
+	return 0, false
+}
+
+func isTruthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case float64:
+		return v != 0
+	}
+
+	return true
+}
+
+type filterTokenKind int
+
+const (
+	filterTokenEOF filterTokenKind = iota
+	filterTokenIdent
+	filterTokenString
+	filterTokenNumber
+	filterTokenOperator
+)
+
+type filterToken struct {
+	kind     filterTokenKind
+	value    string
+	position int
+}
+
+func (t filterToken) String() string {
+	switch t.kind {
+	case filterTokenEOF:
+		return "end of expression"
+	case filterTokenString:
+		return strconv.Quote(t.value)
+	}
+
+	return fmt.Sprintf("%q", t.value)
+}
+
+type filterLexer struct {
+	input    string
+	position int
+}
+
+var filterOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!", "(", ")", "[", "]", ".", ",", "-"}
+
+func (l *filterLexer) next() (filterToken, error) {
+	for l.position < len(l.input) && unicode.IsSpace(rune(l.input[l.position])) {
+		l.position++
+	}
+
+	start := l.position
+	if start >= len(l.input) {
+		return filterToken{kind: filterTokenEOF, position: start}, nil
+	}
+
+	c := l.input[start]
+	switch {
+	case c == '"' || c == '\'':
+		return l.readString(c)
+
+	case c >= '0' && c <= '9':
+		for l.position < len(l.input) && strings.IndexByte("0123456789.eE", l.input[l.position]) != -1 {
+			// The exponent may be signed, like `1e-3`
+			if c := l.input[l.position]; (c == 'e' || c == 'E') && l.position+1 < len(l.input) && (l.input[l.position+1] == '-' || l.input[l.position+1] == '+') {
+				l.position++
+			}
+
+			l.position++
+		}
+
+		return filterToken{kind: filterTokenNumber, value: l.input[start:l.position], position: start}, nil
+
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for l.position < len(l.input) && (l.input[l.position] == '_' || unicode.IsLetter(rune(l.input[l.position])) || unicode.IsDigit(rune(l.input[l.position]))) {
+			l.position++
+		}
+
+		return filterToken{kind: filterTokenIdent, value: l.input[start:l.position], position: start}, nil
+	}
+
+	for _, operator := range filterOperators {
+		if strings.HasPrefix(l.input[start:], operator) {
+			l.position += len(operator)
+			return filterToken{kind: filterTokenOperator, value: operator, position: start}, nil
+		}
+	}
+
+	return filterToken{}, fmt.Errorf("unexpected character %q at position %d", c, start)
+}
+
+func (l *filterLexer) readString(quote byte) (filterToken, error) {
+	start := l.position
+	var value strings.Builder
+
+	for l.position++; l.position < len(l.input); l.position++ {
+		c := l.input[l.position]
+		switch c {
+		case quote:
+			l.position++
+			return filterToken{kind: filterTokenString, value: value.String(), position: start}, nil
+
+		case '\\':
+			l.position++
+			if l.position >= len(l.input) {
+				break
+			}
+
+			switch escaped := l.input[l.position]; escaped {
+			case 'n':
+				value.WriteByte('\n')
+			case 't':
+				value.WriteByte('\t')
+			default:
+				// Unknown escapes are kept as-is so that regular expressions like `\d` work
+				if escaped != quote && escaped != '\\' {
+					value.WriteByte('\\')
+				}
+				value.WriteByte(escaped)
+			}
+
+		default:
+			value.WriteByte(c)
+		}
+	}
+
+	return filterToken{}, fmt.Errorf("unterminated string starting at position %d", start)
+}
+
+type filterParser struct {
+	lexer *filterLexer
+	token filterToken
+}
+
+func (p *filterParser) next() (err error) {
+	p.token, err = p.lexer.next()
+	return err
+}
+
+func (p *filterParser) isOperator(value string) bool {
+	return p.token.kind == filterTokenOperator && p.token.value == value
+}
+
+func (p *filterParser) expectOperator(value string) error {
+	if !p.isOperator(value) {
+		return fmt.Errorf("expected %q but got %s at position %d", value, p.token, p.token.position)
+	}
+
+	return p.next()
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.isOperator("||") {
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+
+		var right filterNode
+		if right, err = p.parseAnd(); err == nil {
+			left = &logicalNode{and: false, left: left, right: right}
+		}
+	}
+
+	return left, err
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	for err == nil && p.isOperator("&&") {
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+
+		var right filterNode
+		if right, err = p.parseUnary(); err == nil {
+			left = &logicalNode{and: true, left: left, right: right}
+		}
+	}
+
+	return left, err
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.isOperator("!") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &notNode{operand: operand}, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.token.kind != filterTokenOperator {
+		return left, nil
+	}
+
+	operator := p.token.value
+	switch operator {
+	case "==", "!=", "<", "<=", ">", ">=":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &compareNode{operator: operator, left: left, right: right}, nil
+
+	case "=~", "!~":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		if p.token.kind != filterTokenString {
+			return nil, fmt.Errorf("expected a regular expression string after %q but got %s at position %d", operator, p.token, p.token.position)
+		}
+
+		regex, err := regexp.Compile(p.token.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression at position %d: %w", p.token.position, err)
+		}
+
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		return &matchNode{negate: operator == "!~", operand: left, regex: regex}, nil
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parsePrimary() (filterNode, error) {
+	token := p.token
+
+	switch token.kind {
+	case filterTokenString:
+		return &literalNode{value: token.value}, p.next()
+
+	case filterTokenNumber:
+		return p.parseNumber(token.value, token.position)
+
+	case filterTokenOperator:
+		switch token.value {
+		case "(":
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+
+			return node, p.expectOperator(")")
+
+		case "-":
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+
+			if p.token.kind != filterTokenNumber {
+				return nil, fmt.Errorf("expected a number after '-' but got %s at position %d", p.token, p.token.position)
+			}
+
+			return p.parseNumber("-"+p.token.value, token.position)
+		}
+
+	case filterTokenIdent:
+		switch token.value {
+		case "true", "false":
+			return &literalNode{value: token.value == "true"}, p.next()
+		case "null":
+			return &literalNode{value: nil}, p.next()
+		case "exists":
+			return p.parseExists()
+		}
+
+		return p.parsePath()
+	}
+
+	return nil, fmt.Errorf("unexpected %s at position %d", token, token.position)
+}
+
+func (p *filterParser) parseNumber(value string, position int) (filterNode, error) {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q at position %d", value, position)
+	}
+
+	return &literalNode{value: number}, p.next()
+}
+
+func (p *filterParser) parseExists() (filterNode, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	if err := p.expectOperator("("); err != nil {
+		return nil, err
+	}
+
+	if p.token.kind != filterTokenIdent {
+		return nil, fmt.Errorf("expected a path in 'exists' but got %s at position %d", p.token, p.token.position)
+	}
+
+	path, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+
+	return &existsNode{path: path}, p.expectOperator(")")
+}
+
+var filterRootIdentifiers = map[string]bool{
+	"level": true, "logger": true, "caller": true, "msg": true, "message": true,
+	"ts": true, "stacktrace": true, "errorVerbose": true, "source": true, "stream": true,
+	"fields": true,
+}
+
+func (p *filterParser) parsePath() (*pathNode, error) {
+	token := p.token
+	if !filterRootIdentifiers[token.value] {
+		return nil, fmt.Errorf("unknown identifier %q at position %d, fields must be accessed with 'fields.%s'", token.value, token.position, token.value)
+	}
+
+	node := &pathNode{root: token.value}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	if node.root != "fields" {
+		return node, nil
+	}
+
+	for {
+		switch {
+		case p.isOperator("."):
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+
+			if p.token.kind != filterTokenIdent {
+				return nil, fmt.Errorf("expected a field name after '.' but got %s at position %d", p.token, p.token.position)
+			}
+
+			node.path = append(node.path, p.token.value)
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+
+		case p.isOperator("["):
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+
+			if p.token.kind != filterTokenString {
+				return nil, fmt.Errorf("expected a field name string after '[' but got %s at position %d", p.token, p.token.position)
+			}
+
+			node.path = append(node.path, p.token.value)
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+
+			if err := p.expectOperator("]"); err != nil {
+				return nil, err
+			}
+
+		default:
+			return node, nil
+		}
+	}
+}
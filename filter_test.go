@@ -0,0 +1,85 @@
+package zapp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterMatch(t *testing.T) {
+	line := `{"level":"warn","ts":1545445711.5,"logger":"p2p","caller":"c.go:1","msg":"dial timeout","peer_count":2,"ok":true,"req":{"id":"abc","weird-key":1},"tags":["a","b"]}`
+
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		{`logger == "p2p"`, true},
+		{`logger == "p2p" && fields.peer_count < 3`, true},
+		{`logger == "p2p" && fields.peer_count >= 3`, false},
+		{`msg =~ "time(out)?$"`, true},
+		{`message !~ "^dial"`, false},
+		{`level == "warn" || level == "error"`, true},
+		{`level >= "warn"`, true},
+		{`level > "warning"`, false},
+		{`level < "error"`, true},
+		{`caller == 'c.go:1'`, true},
+		{`ts > 1545445711`, true},
+		{`fields.ok`, true},
+		{`!fields.ok`, false},
+		{`fields.ok == true`, true},
+		{`fields.req.id == "abc"`, true},
+		{`fields.req["weird-key"] == 1`, true},
+		{`fields.peer_count == "2"`, false},
+		{`fields.peer_count != "2"`, true},
+		{`fields.peer_count > -1.5`, true},
+		{`fields.peer_count > 1e-3`, true},
+		{`fields.peer_count < 2E+1`, true},
+		{`fields.peer_count == 2e0`, true},
+		{`fields.missing == null`, true},
+		{`fields.missing < 3`, false},
+		{`fields.missing =~ "."`, false},
+		{`exists(fields.req.id) && !exists(fields.req.other)`, true},
+		{`exists(stacktrace)`, false},
+		{`fields.tags == fields.tags`, true},
+		{`!(logger == "p2p" && (fields.peer_count == 1 || fields.peer_count == 2))`, false},
+	}
+
+	lineData := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(line), &lineData))
+
+	rec, err := (&Processor{}).parseRecord(lineData)
+	require.NoError(t, err)
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			filter, err := ParseFilter(test.expression)
+			require.NoError(t, err)
+
+			require.Equal(t, test.expected, filter.match(rec))
+		})
+	}
+}
+
+func TestFilterParseErrors(t *testing.T) {
+	tests := []struct {
+		expression    string
+		expectedError string
+	}{
+		{`peer_count < 3`, `invalid filter "peer_count < 3": unknown identifier "peer_count" at position 0, fields must be accessed with 'fields.peer_count'`},
+		{`logger == "p2p`, `invalid filter "logger == \"p2p": unterminated string starting at position 10`},
+		{`msg =~ 12`, `invalid filter "msg =~ 12": expected a regular expression string after "=~" but got "12" at position 7`},
+		{`msg =~ "("`, "invalid filter \"msg =~ \\\"(\\\"\": invalid regular expression at position 7: error parsing regexp: missing closing ): `(`"},
+		{`(level == "info"`, `invalid filter "(level == \"info\"": expected ")" but got end of expression at position 16`},
+		{`level == "info" level`, `invalid filter "level == \"info\" level": unexpected "level" at position 16`},
+		{`exists(12)`, `invalid filter "exists(12)": expected a path in 'exists' but got "12" at position 7`},
+		{`level == @`, `invalid filter "level == @": unexpected character '@' at position 9`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			_, err := ParseFilter(test.expression)
+			require.EqualError(t, err, test.expectedError)
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package zapp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	. "github.com/logrusorgru/aurora"
+)
+
+var (
+	// goroutineDumpStartRegex matches the first line of a Go crash output, a panic, a fatal
+	// error, a signal dump (like the one printed on SIGQUIT) or a goroutine dump
+	goroutineDumpStartRegex = regexp.MustCompile(`^(panic: |fatal error: |SIG[A-Z]+: |goroutine \d+ .*\[.*\]:$)`)
+
+	goroutineHeaderRegex = regexp.MustCompile(`^goroutine (\d+)( .*)? \[(.*)\]:$`)
+
+	// goroutineDumpLineRegexes match the lines that can follow the first line of a crash output
+	goroutineDumpLineRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`^$`),
+		goroutineHeaderRegex,
+		regexp.MustCompile(`^\t?panic: `),
+		regexp.MustCompile(`^fatal error: `),
+		regexp.MustCompile(`^\[signal `),
+		regexp.MustCompile(`^runtime stack:$`),
+		regexp.MustCompile(`^PC=0x`),
+		regexp.MustCompile(`^\w+\s+0x[0-9a-f]+$`),
+		regexp.MustCompile(`^\t.+:\d+( .*)?$`),
+		regexp.MustCompile(`^created by \S+`),
+		regexp.MustCompile(`^\S+\(.*\)$`),
+		regexp.MustCompile(`^\.\.\.`),
+	}
+
+	stackLocationRegex  = regexp.MustCompile(`^(\s*)(.+?):(\d+)( .*)?$`)
+	stackCreatedByRegex = regexp.MustCompile(`^created by (\S+)( in goroutine \d+)?$`)
+)
+
+// goroutineDump is a crash output being read, it's held until its last line so that it can
+// be rendered as a whole.
+type goroutineDump struct {
+	source string
+	stream string
+	lines  []string
+}
+
+// WithRuntimeGoroutinesCollapsed collapses the goroutines of Go crash outputs whose frames
+// are all in the runtime into a single `… N runtime goroutines` line.
+func WithRuntimeGoroutinesCollapsed() ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.collapseRuntimeGoroutines = true
+	})
+}
+
+// consumeGoroutineDumpLine holds the line if it's part of a Go crash output, a `panic:`,
+// `fatal error:` or goroutine dump spanning multiple lines, and reports whether it did. The
+// crash output being held is written as soon as a line not belonging to it is read.
+func (p *Processor) consumeGoroutineDumpLine(source string, stream string, line string) bool {
+	if dump := p.goroutineDump; dump != nil {
+		if dump.source == source && dump.stream == stream && isGoroutineDumpLine(line) {
+			dump.lines = append(dump.lines, line)
+			return true
+		}
+
+		p.flushGoroutineDump()
+	}
+
+	if !goroutineDumpStartRegex.MatchString(line) {
+		return false
+	}
+
+	p.goroutineDump = &goroutineDump{source: source, stream: stream, lines: []string{line}}
+	return true
+}
+
+func isGoroutineDumpLine(line string) bool {
+	for _, regex := range goroutineDumpLineRegexes {
+		if regex.MatchString(line) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// flushGoroutineDump writes the crash output being held, if any.
+func (p *Processor) flushGoroutineDump() {
+	dump := p.goroutineDump
+	if dump == nil {
+		return
+	}
+
+	p.goroutineDump = nil
+	p.flushDedupe()
+
+	// Trailing blank lines are not part of the crash output
+	lines := dump.lines
+	for len(lines) > 1 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	collapsed := 0
+	for i := 0; i < len(lines); {
+		if !goroutineHeaderRegex.MatchString(lines[i]) {
+			p.writeSourceLine(dump.source, p.streamTagged(dump.stream, renderGoroutineDumpLine(lines[i])))
+			i++
+			continue
+		}
+
+		end := i + 1
+		for end < len(lines) && lines[end] != "" && !goroutineHeaderRegex.MatchString(lines[end]) {
+			end++
+		}
+
+		goroutine := lines[i:end]
+		if p.collapseRuntimeGoroutines && isRuntimeGoroutine(goroutine) {
+			collapsed++
+
+			// Skip the blank line separating it from the next goroutine too
+			if end < len(lines) && lines[end] == "" {
+				end++
+			}
+		} else {
+			for _, line := range goroutine {
+				p.writeSourceLine(dump.source, p.streamTagged(dump.stream, renderGoroutineDumpLine(line)))
+			}
+		}
+
+		i = end
+	}
+
+	if collapsed > 0 {
+		text := fmt.Sprintf("… %d runtime goroutines", collapsed)
+		if collapsed == 1 {
+			text = "… 1 runtime goroutine"
+		}
+
+		p.writeSourceLine(dump.source, p.streamTagged(dump.stream, grayText(text)))
+	}
+}
+
+// isRuntimeGoroutine reports whether all the functions of the goroutine, the one that
+// created it included, are in the runtime.
+func isRuntimeGoroutine(lines []string) bool {
+	for _, line := range lines[1:] {
+		function := ""
+		if match := stackCreatedByRegex.FindStringSubmatch(line); match != nil {
+			function = match[1]
+		} else if name, _, ok := splitStackFunction(line); ok {
+			function = name
+		} else {
+			continue
+		}
+
+		if !strings.HasPrefix(function, "runtime.") && !strings.HasPrefix(function, "runtime/") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// splitStackFunction splits a function line of a stack trace like `main.(*T).Run(0xc0000, 0x1)`
+// into the function name and its arguments.
+func splitStackFunction(line string) (string, string, bool) {
+	if line == "" || line[0] == '\t' || line[0] == ' ' || !strings.HasSuffix(line, ")") {
+		return "", "", false
+	}
+
+	depth := 0
+	for i := len(line) - 1; i >= 0; i-- {
+		switch line[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+			if depth == 0 {
+				return line[:i], line[i:], i > 0
+			}
+		}
+	}
+
+	return "", "", false
+}
+
+func renderGoroutineDumpLine(line string) string {
+	if line == "" {
+		return line
+	}
+
+	if match := goroutineHeaderRegex.FindStringSubmatch(line); match != nil {
+		return Bold("goroutine "+match[1]).String() + grayText(match[2]) + " " + Yellow("["+match[3]+"]").String() + ":"
+	}
+
+	if match := stackLocationRegex.FindStringSubmatch(line); match != nil && strings.HasPrefix(line, "\t") {
+		return match[1] + match[2] + ":" + match[3] + grayText(match[4])
+	}
+
+	if match := stackCreatedByRegex.FindStringSubmatch(line); match != nil {
+		return grayText("created by ") + Cyan(match[1]).String() + grayText(match[2])
+	}
+
+	if function, args, ok := splitStackFunction(line); ok {
+		return Cyan(function).String() + grayText(args)
+	}
+
+	switch {
+	case goroutineDumpStartRegex.MatchString(strings.TrimPrefix(line, "\t")), strings.HasPrefix(line, "[signal "):
+		return Colorize(line, RedFg|BoldFm).String()
+	case line == "runtime stack:":
+		return Bold(line).String()
+	default:
+		return grayText(line)
+	}
+}
+
+func grayText(text string) string {
+	if text == "" {
+		return ""
+	}
+
+	return Gray(12, text).String()
+}
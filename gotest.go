@@ -0,0 +1,280 @@
+package zapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	. "github.com/logrusorgru/aurora"
+)
+
+// goTestEvent is an event of `go test -json`, see `go doc test2json`.
+type goTestEvent struct {
+	Action     string
+	Package    string
+	Test       string
+	Elapsed    float64
+	Output     string
+	OutputType string
+}
+
+// goTestGroup is the output of a top-level test, subtests included, it's buffered while
+// another test has the output to itself so that the outputs of parallel tests are not
+// interleaved.
+type goTestGroup struct {
+	key    string
+	source string
+	events []goTestEvent
+	done   bool
+}
+
+// goTestPartial is the output of a test not terminated by a newline yet, along with the
+// source it was read from.
+type goTestPartial struct {
+	source string
+	text   string
+}
+
+// goTestState is the state of `WithGoTestJSON`. The group owning the output is printed as
+// it runs, the others are printed once it's done.
+type goTestState struct {
+	owner   *goTestGroup
+	groups  []*goTestGroup
+	partial map[string]goTestPartial
+}
+
+// WithGoTestJSON reads the output of `go test -json`: the output of the tests is unwrapped
+// and prettified, test start, pass, fail and skip events are rendered as distinct headers
+// and the output of each top-level test is grouped under its header.
+func WithGoTestJSON() ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.goTest = &goTestState{partial: map[string]goTestPartial{}}
+	})
+}
+
+// processGoTestLine processes the line if it's a `go test -json` event, it reports whether
+// the line was one.
+func (p *Processor) processGoTestLine(source string, line string) bool {
+	if p.goTest == nil || !strings.HasPrefix(line, "{") || !strings.Contains(line, `"Action":`) {
+		return false
+	}
+
+	var event goTestEvent
+	if err := json.Unmarshal([]byte(line), &event); err != nil || event.Action == "" {
+		return false
+	}
+
+	if event.Test == "" {
+		p.processGoTestPackageEvent(source, event)
+		return true
+	}
+
+	topLevel := strings.SplitN(event.Test, "/", 2)[0]
+	group := p.goTestGroup(source, event.Package+" "+topLevel)
+	if p.goTest.owner == nil {
+		p.goTest.owner = group
+	}
+
+	if group == p.goTest.owner {
+		p.writeGoTestEvent(source, event)
+	} else {
+		group.events = append(group.events, event)
+	}
+
+	if event.Test == topLevel && isGoTestEnd(event.Action) {
+		group.done = true
+		if group == p.goTest.owner {
+			p.goTest.owner = nil
+			p.flushGoTestGroups(false)
+		}
+	}
+
+	return true
+}
+
+func (p *Processor) processGoTestPackageEvent(source string, event goTestEvent) {
+	switch event.Action {
+	case "output", "build-output":
+		p.writeGoTestOutput(source, event)
+
+	case "pass", "fail", "skip":
+		// The package is done, its tests are done too unless the test binary crashed
+		p.flushGoTestGroups(true)
+		p.writeGoTestEvent(source, event)
+	}
+}
+
+func (p *Processor) goTestGroup(source string, key string) *goTestGroup {
+	for _, group := range p.goTest.groups {
+		if group.source == source && group.key == key {
+			return group
+		}
+	}
+
+	group := &goTestGroup{key: key, source: source}
+	p.goTest.groups = append(p.goTest.groups, group)
+
+	return group
+}
+
+// flushGoTestGroups prints the buffered groups of the tests that are done, then the first
+// one still running which becomes the owner of the output. With `all`, every group is
+// printed whether it's done or not.
+func (p *Processor) flushGoTestGroups(all bool) {
+	var running []*goTestGroup
+	for _, group := range p.goTest.groups {
+		if group == p.goTest.owner {
+			running = append(running, group)
+			continue
+		}
+
+		if !group.done && !all {
+			if p.goTest.owner == nil {
+				p.goTest.owner = group
+				p.writeGoTestEvents(group)
+			}
+
+			running = append(running, group)
+			continue
+		}
+
+		p.writeGoTestEvents(group)
+	}
+
+	p.goTest.groups = running
+	if all {
+		p.goTest.owner = nil
+		p.goTest.groups = nil
+	}
+}
+
+func (p *Processor) writeGoTestEvents(group *goTestGroup) {
+	for _, event := range group.events {
+		p.writeGoTestEvent(group.source, event)
+	}
+
+	group.events = nil
+}
+
+func (p *Processor) writeGoTestEvent(source string, event goTestEvent) {
+	var header string
+	switch event.Action {
+	case "output":
+		p.writeGoTestOutput(source, event)
+		return
+
+	case "run":
+		header = goTestIndent(event) + Colorize("=== RUN   "+event.Test, CyanFg|BoldFm).String()
+
+	case "pass", "fail", "skip":
+		if event.Test == "" {
+			header = goTestPackageResult(event)
+		} else {
+			header = goTestIndent(event) + goTestResult(event)
+		}
+
+	default:
+		return
+	}
+
+	p.flushGoroutineDump()
+	p.flushDedupe()
+	p.writeSourceLine(source, header)
+}
+
+// goTestIndent indents the headers of subtests by their depth like `go test -v` does.
+func goTestIndent(event goTestEvent) string {
+	return strings.Repeat("    ", strings.Count(event.Test, "/"))
+}
+
+func goTestResult(event goTestEvent) string {
+	text := fmt.Sprintf("--- %s: %s (%.2fs)", strings.ToUpper(event.Action), event.Test, event.Elapsed)
+
+	switch event.Action {
+	case "pass":
+		return Green(text).String()
+	case "fail":
+		return Colorize(text, RedFg|BoldFm).String()
+	default:
+		return Yellow(text).String()
+	}
+}
+
+func goTestPackageResult(event goTestEvent) string {
+	switch event.Action {
+	case "pass":
+		return Green(fmt.Sprintf("ok  \t%s\t%.3fs", event.Package, event.Elapsed)).String()
+	case "fail":
+		return Colorize(fmt.Sprintf("FAIL\t%s\t%.3fs", event.Package, event.Elapsed), RedFg|BoldFm).String()
+	default:
+		return Yellow(fmt.Sprintf("?   \t%s\t[no test files]", event.Package)).String()
+	}
+}
+
+// writeGoTestOutput processes the output of the event, output without newline is kept until
+// the rest of the line arrives. The lines written by the testing framework itself, like
+// `--- PASS: TestX`, are dropped since their events are rendered instead.
+func (p *Processor) writeGoTestOutput(source string, event goTestEvent) {
+	key := source + " " + event.Package + " " + event.Test
+	output := p.goTest.partial[key].text + event.Output
+	delete(p.goTest.partial, key)
+
+	if !strings.HasSuffix(output, "\n") {
+		p.goTest.partial[key] = goTestPartial{source: source, text: output}
+		return
+	}
+
+	line := strings.TrimSuffix(strings.TrimSuffix(output, "\n"), "\r")
+	if event.OutputType == "frame" || isGoTestFrameworkLine(line) {
+		return
+	}
+
+	p.processUnwrappedLine(source, "", line)
+}
+
+// flushGoTest prints what's left of the tests once the input ends, like when the test
+// binary crashed before reporting their end.
+func (p *Processor) flushGoTest() {
+	if p.goTest == nil {
+		return
+	}
+
+	p.flushGoTestGroups(true)
+
+	keys := make([]string, 0, len(p.goTest.partial))
+	for key := range p.goTest.partial {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		partial := p.goTest.partial[key]
+		p.processUnwrappedLine(partial.source, "", partial.text)
+	}
+	p.goTest.partial = map[string]goTestPartial{}
+}
+
+func isGoTestEnd(action string) bool {
+	return action == "pass" || action == "fail" || action == "skip"
+}
+
+var goTestFrameworkPrefixes = []string{
+	"=== RUN", "=== PAUSE", "=== CONT", "=== NAME",
+	"--- PASS:", "--- FAIL:", "--- SKIP:",
+}
+
+func isGoTestFrameworkLine(line string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	for _, prefix := range goTestFrameworkPrefixes {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+
+	if line == "PASS" || line == "FAIL" || strings.HasPrefix(line, "ok  \t") || strings.HasPrefix(line, "FAIL\t") || strings.HasPrefix(line, "?   \t") {
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,91 @@
+package zapp
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	. "github.com/logrusorgru/aurora"
+)
+
+var headerFieldsPalette = []Color{CyanFg, MagentaFg, YellowFg, GreenFg, BrightFg | BlueFg, BrightFg | RedFg}
+
+// HeaderField is a field promoted from the fields block into the header line, rendered
+// as `[label=value]` right before the message.
+type HeaderField struct {
+	// Key is the key of the field or its dotted path for nested fields
+	Key string
+
+	// Label is the name rendered in the header, defaults to `Key` when empty
+	Label string
+
+	// Color used to render the field, a color from a default palette is picked when 0
+	Color Color
+}
+
+// ParseHeaderField parses a header field specification of the form `key[=label][:color]`,
+// for example `request_id=req:yellow`.
+func ParseHeaderField(spec string) (HeaderField, error) {
+	field := HeaderField{}
+
+	if index := strings.LastIndex(spec, ":"); index != -1 {
+		color, err := ParseColor(spec[index+1:])
+		if err != nil {
+			return field, fmt.Errorf("invalid header field %q: %w", spec, err)
+		}
+
+		field.Color = color
+		spec = spec[:index]
+	}
+
+	field.Key, field.Label, _ = strings.Cut(spec, "=")
+	if field.Key == "" {
+		return field, fmt.Errorf("invalid header field %q: key is required", spec)
+	}
+
+	return field, nil
+}
+
+type headerFieldValue struct {
+	label string
+	value string
+	color Color
+}
+
+// extractHeaderFields pulls the configured header fields out of data, the values found
+// are returned along with a copy of data without them.
+func (p *Processor) extractHeaderFields(data map[string]interface{}) ([]headerFieldValue, map[string]interface{}) {
+	if len(p.headerFields) == 0 {
+		return nil, data
+	}
+
+	remaining := copyObject(data)
+	values := make([]headerFieldValue, 0, len(p.headerFields))
+	for _, field := range p.headerFields {
+		value, found := removeFieldPath(remaining, field.Key)
+		if !found {
+			continue
+		}
+
+		label := field.Label
+		if label == "" {
+			label = field.Key
+		}
+
+		values = append(values, headerFieldValue{label: label, value: p.highlightField(field.Key, fieldValueToString(value)), color: field.Color})
+	}
+
+	return values, remaining
+}
+
+func writeHeaderFields(buffer *bytes.Buffer, values []headerFieldValue) {
+	buffer.WriteString(Gray(12, "[").String())
+	for i, value := range values {
+		if i != 0 {
+			buffer.WriteByte(' ')
+		}
+
+		buffer.WriteString(Colorize(value.label+"="+value.value, value.color).String())
+	}
+	buffer.WriteString(Gray(12, "]").String())
+}
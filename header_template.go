@@ -0,0 +1,183 @@
+package zapp
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	. "github.com/logrusorgru/aurora"
+)
+
+// DefaultHeaderFormat is the header template used when none is provided, it renders
+// `[time, relative] LEVEL (logger, caller) [header fields] message`, lines detected as out of
+// order have a marker like `↶ -1.2s` right after the time and lines coming from a named
+// source are prefixed by its tag like `api |`, followed by the container stream if shown.
+const DefaultHeaderFormat = `{{with .Source}}{{.}} {{end}}{{with .Stream}}{{. | color "gray"}} {{end}}[{{.Timestamp}}{{with .Relative}}, {{.}}{{end}}]{{with .OutOfOrder}} {{.}}{{end}} {{.Level | levelColor}}` +
+	`{{with .Origin}} {{printf "(%s)" . | color "gray"}}{{end}}` +
+	`{{with .HeaderFields}} {{.}}{{end}}` +
+	` {{.Message | color "blue"}}`
+
+var defaultHeaderTemplate = MustNewHeaderTemplate(DefaultHeaderFormat)
+
+// HeaderData is the data available to the header template.
+type HeaderData struct {
+	// Time is the timestamp of the log line
+	Time time.Time
+
+	// Timestamp is `Time` formatted with the configured time format
+	Timestamp string
+
+	// Delta is the duration since the last log line, nil if there is none
+	Delta *time.Duration
+
+	// Relative is the relative time rendered according to the time mode, empty when none
+	Relative string
+
+	// OutOfOrder is the already rendered marker like `↶ -1.2s` when the line jumped back in
+	// time, empty otherwise
+	OutOfOrder string
+
+	// Level is the upper-cased level of the log line
+	Level string
+
+	// Logger is the name of the logger, empty if absent
+	Logger string
+
+	// Caller is the caller of the log statement, empty if absent
+	Caller string
+
+	// Origin is `Logger` and `Caller` joined by a comma, skipping empty ones
+	Origin string
+
+	// HeaderFields is the already rendered `[label=value ...]` block of promoted fields, empty if none
+	HeaderFields string
+
+	// Message is the log message
+	Message string
+
+	// Source is the already rendered tag like `api |` of the input the line comes from, empty
+	// when there is a single input
+	Source string
+
+	// Stream is the stream, like `stderr`, of the container log envelope the line was
+	// unwrapped from, empty unless streams are shown
+	Stream string
+}
+
+// HeaderTemplate is a parsed `text/template` used to render the header of each log line,
+// see `NewHeaderTemplate` for the available helpers.
+type HeaderTemplate struct {
+	template *template.Template
+}
+
+// NewHeaderTemplate parses `format` as a Go `text/template` executed against `HeaderData`.
+// On top of the standard functions, the following helpers are available:
+//
+//   - `color NAME VALUE` colorizes value, see `ColorNames` for accepted names
+//   - `levelColor VALUE` colorizes value using the color of the level it contains
+//   - `pad WIDTH VALUE` pads value with spaces to width, right-aligned when width is negative
+//   - `truncate WIDTH VALUE` truncates value to width characters, ending with `…` if cut
+//   - `fmt LAYOUT TIME` formats the time using a Go time layout
+//   - `delta DURATION` formats a duration like the delta time mode does, `-` if nil
+//   - `upper VALUE` and `lower VALUE` change the case of value
+//
+// The template is executed once against sample data so that errors like unknown fields or
+// colors are reported right away instead of on each line.
+func NewHeaderTemplate(format string) (*HeaderTemplate, error) {
+	tmpl, err := template.New("header").Funcs(headerTemplateFuncs).Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid header format: %w", err)
+	}
+
+	headerTemplate := &HeaderTemplate{template: tmpl}
+	if err := headerTemplate.execute(&bytes.Buffer{}, sampleHeaderData()); err != nil {
+		return nil, fmt.Errorf("invalid header format: %w", err)
+	}
+
+	return headerTemplate, nil
+}
+
+// sampleHeaderData is the data `NewHeaderTemplate` validates templates against, every
+// field is set.
+func sampleHeaderData() *HeaderData {
+	delta := 1200 * time.Millisecond
+	now := time.Now()
+
+	return &HeaderData{
+		Time:         now,
+		Timestamp:    now.Format(time.RFC3339),
+		Delta:        &delta,
+		Relative:     "+1.2s",
+		OutOfOrder:   "↶ -1.2s",
+		Level:        "INFO",
+		Logger:       "logger",
+		Caller:       "main.go:12",
+		Origin:       "logger, main.go:12",
+		HeaderFields: "[key=value]",
+		Message:      "message",
+		Source:       "api |",
+		Stream:       "stderr",
+	}
+}
+
+// MustNewHeaderTemplate is like `NewHeaderTemplate` but panics on error.
+func MustNewHeaderTemplate(format string) *HeaderTemplate {
+	tmpl, err := NewHeaderTemplate(format)
+	if err != nil {
+		panic(err)
+	}
+
+	return tmpl
+}
+
+func (t *HeaderTemplate) execute(buffer *bytes.Buffer, data *HeaderData) error {
+	return t.template.Execute(buffer, data)
+}
+
+var headerTemplateFuncs = template.FuncMap{
+	"color": func(name string, value string) (string, error) {
+		color, err := ParseColor(name)
+		if err != nil {
+			return "", err
+		}
+
+		return Colorize(value, color).String(), nil
+	},
+	"levelColor": func(value string) string {
+		return Colorize(value, severityColor(strings.TrimSpace(value))).String()
+	},
+	"pad": func(width int, value string) string {
+		if width < 0 {
+			return fmt.Sprintf("%*s", -width, value)
+		}
+
+		return fmt.Sprintf("%-*s", width, value)
+	},
+	"truncate": func(width int, value string) string {
+		if width <= 0 || utf8.RuneCountInString(value) <= width {
+			return value
+		}
+
+		runes := []rune(value)
+		if width == 1 {
+			return "…"
+		}
+
+		return string(runes[:width-1]) + "…"
+	},
+	"fmt": func(layout string, value time.Time) string {
+		return value.Format(layout)
+	},
+	"delta": func(value *time.Duration) string {
+		if value == nil {
+			return "-"
+		}
+
+		return durationToString(*value)
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
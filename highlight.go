@@ -0,0 +1,220 @@
+package zapp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Highlight marks the matches of a regular expression in the message and in field values,
+// numbers and booleans are matched against their JSON text. The highlight is only applied to
+// fields matching `Field` when it's set.
+type Highlight struct {
+	// Field is the glob pattern of the dotted field path the highlight is restricted to (see
+	// `WithHiddenFields` for the syntax), `msg` or `message` target the log message, empty
+	// means everywhere
+	Field string
+
+	// Pattern is the regular expression to highlight
+	Pattern *regexp.Regexp
+}
+
+// highlightStart and highlightEnd turn on and off the inverse and bold styles without
+// resetting the other attributes so that the highlight can be nested inside colored text.
+const (
+	highlightStart = "\x1b[1;7m"
+	highlightEnd   = "\x1b[22;27m"
+)
+
+// highlightStartMarker and highlightEndMarker are private use characters inserted in
+// string values before they are serialized to JSON, they are replaced by the escape codes
+// afterwards so that the escape codes are not themselves escaped by the JSON encoder.
+const (
+	highlightStartMarker = "\uE000"
+	highlightEndMarker   = "\uE001"
+)
+
+// highlightRawStartMarker and highlightRawEndMarker surround the string replacing a number or
+// boolean value that has a match, the quotes around it are removed along with the markers so
+// that the value is still rendered as a number or boolean.
+const (
+	highlightRawStartMarker = "\uE002"
+	highlightRawEndMarker   = "\uE003"
+)
+
+var highlightFieldRegex = regexp.MustCompile(`^[\w.*?/-]+$`)
+
+// ParseHighlight parses a highlight definition of the form `[field=~]pattern`, where
+// `pattern` is a Go regular expression and the optional `field` a glob pattern restricting
+// the highlight to the matching dotted field paths. Use `msg` as the field to only
+// highlight the message.
+func ParseHighlight(value string) (Highlight, error) {
+	var highlight Highlight
+
+	pattern := value
+	if field, rest, found := strings.Cut(value, "=~"); found && highlightFieldRegex.MatchString(field) {
+		highlight.Field = field
+		pattern = rest
+	}
+
+	if pattern == "" {
+		return highlight, fmt.Errorf("empty pattern in highlight %q", value)
+	}
+
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return highlight, fmt.Errorf("invalid pattern in highlight %q: %w", value, err)
+	}
+
+	highlight.Pattern = regex
+	return highlight, nil
+}
+
+// MustParseHighlight is like `ParseHighlight` but panics on error.
+func MustParseHighlight(value string) Highlight {
+	highlight, err := ParseHighlight(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return highlight
+}
+
+func (h Highlight) appliesToMessage() bool {
+	return h.Field == "" || h.Field == "msg" || h.Field == "message"
+}
+
+// appliesToField tells if the highlight applies to the field at `path`, a highlight scoped
+// to `msg` or `message` only applies to the log message, not to a field of the same name.
+func (h Highlight) appliesToField(path string) bool {
+	if h.Field == "msg" || h.Field == "message" {
+		return false
+	}
+
+	return h.Field == "" || matchFieldPattern(h.Field, path)
+}
+
+// highlightMessage returns the message with the matches of the applicable highlights
+// surrounded by the highlight escape codes.
+func (p *Processor) highlightMessage(message string) string {
+	return p.highlightText(message, highlightStart, highlightEnd, Highlight.appliesToMessage)
+}
+
+// highlightField is like `highlightMessage` but for the value of the field at `path`.
+func (p *Processor) highlightField(path string, value string) string {
+	return p.highlightText(value, highlightStart, highlightEnd, func(h Highlight) bool {
+		return h.appliesToField(path)
+	})
+}
+
+func (p *Processor) highlightText(text string, start string, end string, applies func(h Highlight) bool) string {
+	var ranges [][]int
+	for _, highlight := range p.highlights {
+		if !applies(highlight) {
+			continue
+		}
+
+		for _, match := range highlight.Pattern.FindAllStringIndex(text, -1) {
+			if match[0] < match[1] {
+				ranges = append(ranges, match)
+			}
+		}
+	}
+
+	if len(ranges) == 0 {
+		return text
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+
+	var builder strings.Builder
+	position := 0
+	for i := 0; i < len(ranges); i++ {
+		from, to := ranges[i][0], ranges[i][1]
+		for i+1 < len(ranges) && ranges[i+1][0] <= to {
+			i++
+			if ranges[i][1] > to {
+				to = ranges[i][1]
+			}
+		}
+
+		builder.WriteString(text[position:from])
+		builder.WriteString(start)
+		builder.WriteString(text[from:to])
+		builder.WriteString(end)
+		position = to
+	}
+	builder.WriteString(text[position:])
+
+	return builder.String()
+}
+
+// highlightFields returns a copy of data where the matches in values are surrounded by the
+// highlight markers, `applyHighlightMarkers` must be called on the serialized output.
+func (p *Processor) highlightFields(data map[string]interface{}) map[string]interface{} {
+	if len(p.highlights) == 0 {
+		return data
+	}
+
+	return p.highlightObject("", data)
+}
+
+func (p *Processor) highlightObject(path string, data map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		out[key] = p.highlightValue(joinFieldPath(path, key), value)
+	}
+
+	return out
+}
+
+func (p *Processor) highlightValue(path string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return p.highlightText(v, highlightStartMarker, highlightEndMarker, func(h Highlight) bool {
+			return h.appliesToField(path)
+		})
+
+	case float64, bool:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return value
+		}
+
+		highlighted := p.highlightText(string(raw), highlightStartMarker, highlightEndMarker, func(h Highlight) bool {
+			return h.appliesToField(path)
+		})
+		if highlighted == string(raw) {
+			return value
+		}
+
+		return highlightRawStartMarker + highlighted + highlightRawEndMarker
+
+	case map[string]interface{}:
+		return p.highlightObject(path, v)
+
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, element := range v {
+			out[i] = p.highlightValue(path, element)
+		}
+
+		return out
+	}
+
+	return value
+}
+
+func applyHighlightMarkers(data []byte) []byte {
+	if len(data) == 0 || !bytes.Contains(data, []byte(highlightStartMarker)) {
+		return data
+	}
+
+	data = bytes.ReplaceAll(data, []byte(`"`+highlightRawStartMarker), nil)
+	data = bytes.ReplaceAll(data, []byte(highlightRawEndMarker+`"`), nil)
+	data = bytes.ReplaceAll(data, []byte(highlightStartMarker), []byte(highlightStart))
+	return bytes.ReplaceAll(data, []byte(highlightEndMarker), []byte(highlightEnd))
+}
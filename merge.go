@@ -0,0 +1,194 @@
+package zapp
+
+import (
+	"bufio"
+	"container/heap"
+	"time"
+)
+
+// mergeLine is a line of a source once decoded, `rec` is nil when it's not a log line in
+// which case `nonJSON` tells if it was at least a JSON object. The `source` is the one named
+// by the line prefix, if any, the name of the source it was read from otherwise.
+type mergeLine struct {
+	text    string
+	source  string
+	rec     *record
+	nonJSON bool
+}
+
+// mergeGroup is a record along with the lines that are not log lines following it in its
+// source, they are kept together when merging. The lines found before the first record of
+// a source form a group without record sorted before all others.
+type mergeGroup struct {
+	source    int
+	record    *mergeLine
+	attached  []mergeLine
+	timestamp time.Time
+}
+
+type mergeReader struct {
+	index         int
+	name          string
+	scanner       *bufio.Scanner
+	pending       *mergeGroup
+	lastTimestamp time.Time
+}
+
+// next returns the next group of the source, nil once the source is exhausted. A group is
+// only complete once the next record is read, the latter is kept as the pending group.
+func (r *mergeReader) next(p *Processor) *mergeGroup {
+	for r.scanner.Scan() {
+		line, complete := p.decodeMergeLine(r.name, r.scanner.Text())
+		if !complete {
+			continue
+		}
+
+		if line.rec == nil {
+			if r.pending == nil {
+				r.pending = &mergeGroup{source: r.index}
+			}
+
+			r.pending.attached = append(r.pending.attached, line)
+			continue
+		}
+
+		// Records without timestamp are kept right after the previous record of their source
+		if line.rec.timestamp != nil {
+			r.lastTimestamp = *line.rec.timestamp
+		}
+
+		group := r.pending
+		r.pending = &mergeGroup{source: r.index, record: &line, timestamp: r.lastTimestamp}
+		if group != nil {
+			return group
+		}
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		p.debugPrintln("Scanner of source %d terminated with error: %s", r.index, err)
+	}
+
+	group := r.pending
+	r.pending = nil
+
+	return group
+}
+
+// decodeMergeLine decodes a line read from the named source, false is returned for the
+// parts of a line split by the container runtime preceding the last one.
+func (p *Processor) decodeMergeLine(source string, text string) (mergeLine, bool) {
+	if prefixSource, rest, found := p.splitLinePrefix(text); found {
+		source, text = prefixSource, rest
+	}
+
+	text, stream, complete := p.unwrapContainerLine(source, text)
+	if !complete {
+		return mergeLine{}, false
+	}
+
+	lineData, err := decodeJSONLine(text)
+	if err != nil {
+		return mergeLine{text: p.streamTagged(stream, text), source: source, nonJSON: true}, true
+	}
+
+	rec, err := p.parseRecord(lineData)
+	if err != nil {
+		return mergeLine{text: p.streamTagged(stream, text), source: source}, true
+	}
+
+	rec.stream = stream
+	return mergeLine{text: text, source: source, rec: rec}, true
+}
+
+// mergeHeap orders the next group of each source by timestamp, groups having the same
+// timestamp are ordered by source.
+type mergeHeap []*mergeGroup
+
+func (h mergeHeap) Len() int { return len(h) }
+
+func (h mergeHeap) Less(i, j int) bool {
+	if !h[i].timestamp.Equal(h[j].timestamp) {
+		return h[i].timestamp.Before(h[j].timestamp)
+	}
+
+	return h[i].source < h[j].source
+}
+
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(*mergeGroup)) }
+
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	group := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return group
+}
+
+// Merge processes the sources together instead of the processor's scanner, their records
+// are printed in timestamp order, each one tagged with the name of its source. Lines that
+// are not log lines stay attached to the record preceding them in their source.
+func (p *Processor) Merge(sources ...Source) {
+	for _, source := range sources {
+		p.registerSource(source.Name)
+	}
+
+	readers := make([]*mergeReader, len(sources))
+	groups := &mergeHeap{}
+	for i, source := range sources {
+		readers[i] = &mergeReader{index: i, name: source.Name, scanner: source.Scanner}
+		if group := readers[i].next(p); group != nil {
+			*groups = append(*groups, group)
+		}
+	}
+	heap.Init(groups)
+
+	for groups.Len() > 0 {
+		group := heap.Pop(groups).(*mergeGroup)
+		p.processMergeGroup(group)
+
+		if next := readers[group.source].next(p); next != nil {
+			heap.Push(groups, next)
+		}
+	}
+
+	p.finish()
+}
+
+func (p *Processor) processMergeGroup(group *mergeGroup) {
+	p.flushGoroutineDump()
+
+	if line := group.record; line != nil {
+		p.processMergeRecord(line)
+	}
+
+	for _, line := range group.attached {
+		if line.nonJSON {
+			p.stats.countNonJSON()
+		} else {
+			p.stats.countUnparseable()
+		}
+		p.statusBar.countNonRecord()
+
+		if p.consumeGoroutineDumpLine(line.source, "", line.text) {
+			continue
+		}
+
+		p.flushDedupe()
+		p.writeSourceLine(line.source, line.text)
+	}
+}
+
+// processMergeRecord prints the record of a group, the line is printed as is if rendering
+// it panics, like for lines read from a single input.
+func (p *Processor) processMergeRecord(line *mergeLine) {
+	defer func() {
+		if err := recover(); err != nil {
+			p.unformattedPrintLine(line.source, p.streamTagged(line.rec.stream, line.text), "Panic occurred while processing line '%s', ending processing (%s)", line.text, err)
+		}
+	}()
+
+	line.rec.source = line.source
+	p.processRecord(line.text, line.rec)
+}
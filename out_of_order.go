@@ -0,0 +1,47 @@
+package zapp
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/logrusorgru/aurora"
+)
+
+// checkOutOfOrder reports how far back in time the record jumped compared to the previous
+// line, only when out-of-order detection is enabled and the jump exceeds the tolerance.
+func (p *Processor) checkOutOfOrder(rec *record) (time.Duration, bool) {
+	if !p.outOfOrderDetection || rec.timestamp == nil || p.lastProcessedTimestamp == nil {
+		return 0, false
+	}
+
+	jump := p.lastProcessedTimestamp.Sub(*rec.timestamp)
+	if jump <= p.outOfOrderTolerance {
+		return 0, false
+	}
+
+	p.outOfOrderCount++
+	if jump > p.outOfOrderLargestJump {
+		p.outOfOrderLargestJump = jump
+	}
+
+	return jump, true
+}
+
+func outOfOrderMarker(jump time.Duration) string {
+	return Colorize("↶ -"+durationToString(jump), MagentaFg|BoldFm).String()
+}
+
+func (p *Processor) writeOutOfOrderSummary() {
+	if !p.outOfOrderDetection || p.summaryOutput == nil {
+		return
+	}
+
+	p.terminateOutput()
+	if p.outOfOrderCount == 0 {
+		fmt.Fprintln(p.summaryOutput, "No out-of-order lines detected")
+		return
+	}
+
+	fmt.Fprintf(p.summaryOutput, "Detected %d out-of-order line(s) (tolerance %s, largest backwards jump %s)\n",
+		p.outOfOrderCount, durationToString(p.outOfOrderTolerance), durationToString(p.outOfOrderLargestJump))
+}
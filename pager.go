@@ -0,0 +1,626 @@
+package zapp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	. "github.com/logrusorgru/aurora"
+	"golang.org/x/term"
+)
+
+// pagerLevels are the level categories that can be toggled in the pager, the key toggling
+// each of them is its first letter in lower case. Levels more severe than error are part
+// of the error category.
+var pagerLevels = []string{"DEBUG", "INFO", "WARN", "ERROR"}
+
+// pagerEntry is a line of the file shown by the pager, `rec` is nil for lines that are not
+// log lines.
+type pagerEntry struct {
+	line     string
+	rec      *record
+	header   string
+	search   string
+	level    int
+	expanded bool
+	details  []string
+}
+
+// Pager is an interactive terminal viewer of a log file, each record is shown on a single
+// line rendered by the header renderer and can be expanded to show all its fields and its
+// error details. It supports incremental search, level toggles and following the file as
+// it grows.
+type Pager struct {
+	processor *Processor
+	entries   []*pagerEntry
+	visible   []int
+	hidden    map[int]bool
+
+	selected int
+	top      int
+
+	searching    bool
+	query        string
+	searchOrigin int
+
+	follow  bool
+	message string
+	quit    bool
+}
+
+// NewPager returns a pager rendering records with a processor configured with `opts`.
+func NewPager(opts ...ProcessorOption) *Pager {
+	processor := NewProcessor(nil, io.Discard, opts...)
+	processor.summaryOutput = nil
+
+	return &Pager{processor: processor, hidden: map[int]bool{}}
+}
+
+// Run shows the log file at `path` until the user quits, standard input and output must
+// be a terminal.
+func (p *Pager) Run(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("unable to open file: %w", err)
+	}
+	defer file.Close()
+
+	inputFd, outputFd := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if !term.IsTerminal(inputFd) || !term.IsTerminal(outputFd) {
+		return fmt.Errorf("the pager requires standard input and output to be a terminal")
+	}
+
+	state, err := term.MakeRaw(inputFd)
+	if err != nil {
+		return fmt.Errorf("unable to configure terminal: %w", err)
+	}
+	defer term.Restore(inputFd, state)
+
+	// Alternate screen and hidden cursor, restored on exit
+	io.WriteString(os.Stdout, "\x1b[?1049h\x1b[?25l")
+	defer io.WriteString(os.Stdout, "\x1b[?25h\x1b[?1049l")
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	lines := make(chan string, 1024)
+	go tailFile(file, lines, stop)
+
+	keys := make(chan string)
+	go readKeys(os.Stdin, keys)
+
+	// Polled instead of relying on `SIGWINCH` which is not available everywhere
+	resize := time.NewTicker(250 * time.Millisecond)
+	defer resize.Stop()
+
+	var width, height int
+	for dirty := true; !p.quit; {
+		if newWidth, newHeight, err := term.GetSize(outputFd); err == nil && (newWidth != width || newHeight != height) {
+			width, height, dirty = newWidth, newHeight, true
+		}
+
+		if dirty {
+			io.WriteString(os.Stdout, "\x1b[H"+strings.Join(p.render(width, height), "\x1b[K\r\n")+"\x1b[K")
+			dirty = false
+		}
+
+		select {
+		case key, ok := <-keys:
+			if !ok {
+				return nil
+			}
+
+			p.handleKey(key)
+			dirty = true
+
+		case line := <-lines:
+			p.appendLine(line)
+
+			// Add the lines already available before redrawing to load files quickly
+			for drained := false; !drained; {
+				select {
+				case line := <-lines:
+					p.appendLine(line)
+				default:
+					drained = true
+				}
+			}
+			dirty = true
+
+		case <-resize.C:
+		}
+	}
+
+	return nil
+}
+
+// tailFile sends the lines of the file on `lines`, once the end of the file is reached, it
+// waits for new lines to be appended until `stop` is closed.
+func tailFile(file *os.File, lines chan<- string, stop <-chan struct{}) {
+	reader := bufio.NewReaderSize(file, 64*1024)
+
+	var partial strings.Builder
+	for {
+		chunk, err := reader.ReadString('\n')
+		partial.WriteString(chunk)
+
+		if err == nil {
+			select {
+			case lines <- strings.TrimRight(partial.String(), "\r\n"):
+			case <-stop:
+				return
+			}
+
+			partial.Reset()
+			continue
+		}
+
+		if err != io.EOF {
+			return
+		}
+
+		select {
+		case <-time.After(250 * time.Millisecond):
+		case <-stop:
+			return
+		}
+	}
+}
+
+// readKeys reads the keys pressed in the terminal, escape sequences of special keys are
+// sent as a whole.
+func readKeys(input io.Reader, keys chan<- string) {
+	defer close(keys)
+
+	buffer := make([]byte, 256)
+	for {
+		n, err := input.Read(buffer)
+		if err != nil {
+			return
+		}
+
+		for _, key := range splitKeys(buffer[:n]) {
+			keys <- key
+		}
+	}
+}
+
+// splitKeys splits the bytes read from a terminal into keys, either a single character or a
+// whole `ESC [ ... final` escape sequence.
+func splitKeys(data []byte) (keys []string) {
+	for len(data) > 0 {
+		if data[0] == 0x1b && len(data) > 2 && (data[1] == '[' || data[1] == 'O') {
+			end := 2
+			for end < len(data) && (data[end] < 0x40 || data[end] > 0x7e) {
+				end++
+			}
+
+			if end < len(data) {
+				end++
+			}
+
+			keys = append(keys, string(data[:end]))
+			data = data[end:]
+			continue
+		}
+
+		_, size := utf8.DecodeRune(data)
+		keys = append(keys, string(data[:size]))
+		data = data[size:]
+	}
+
+	return keys
+}
+
+func (p *Pager) appendLine(line string) {
+	source, text, _ := p.processor.splitLinePrefix(line)
+	text, stream, complete := p.processor.unwrapContainerLine(source, text)
+	if !complete {
+		return
+	}
+
+	if stream != "" {
+		// Unwrapped from a container log envelope, the envelope itself is of no interest
+		line = text
+	}
+
+	entry := &pagerEntry{line: line, search: strings.ToLower(line)}
+
+	if lineData, err := decodeJSONLine(text); err == nil {
+		if rec, err := p.processor.parseRecord(lineData); err == nil {
+			rec.source = source
+			rec.stream = stream
+			entry.rec = rec
+			entry.level = pagerLevel(rec.severity)
+			entry.search = strings.ToLower(strings.Join([]string{rec.message, recordLoggerKey(rec), fieldValueToString(rec.fields), rec.errorVerboseText()}, "\n"))
+		}
+	}
+
+	entry.header = p.renderHeader(entry)
+
+	p.entries = append(p.entries, entry)
+	if p.isVisible(entry) {
+		p.visible = append(p.visible, len(p.entries)-1)
+
+		if p.follow {
+			p.selected = len(p.visible) - 1
+		}
+	}
+}
+
+func pagerLevel(severity string) int {
+	rank, found := levelsRank[strings.ToLower(severity)]
+	if !found {
+		return levelsRank["info"]
+	}
+
+	if rank > levelsRank["error"] {
+		return levelsRank["error"]
+	}
+
+	return rank
+}
+
+// renderHeader renders the single line shown for the entry in the list, headers are
+// rendered once in order so that relative times are computed against the previous record.
+func (p *Pager) renderHeader(entry *pagerEntry) string {
+	if entry.rec == nil {
+		return sanitizePagerLine(entry.line)
+	}
+
+	headerFields, fields := p.processor.extractHeaderFields(entry.rec.renderedFields())
+
+	var buffer bytes.Buffer
+	if err := p.processor.writeHeader(&buffer, entry.rec, headerFields); err != nil {
+		return sanitizePagerLine(entry.line)
+	}
+
+	// Drop the slow gap separator, if any, the list shows a single line per record
+	header := buffer.String()
+	if index := strings.LastIndexByte(header, '\n'); index >= 0 {
+		header = header[index+1:]
+	}
+
+	if fields = p.processor.selectFields(fields, entry.rec.hiddenFields); len(fields) > 0 {
+		if compact, err := json.Marshal(fields); err == nil {
+			header += " " + string(compact)
+		}
+	}
+
+	return sanitizePagerLine(header)
+}
+
+// renderDetails renders the lines shown below an expanded record: all its fields, without
+// hiding nor truncation, followed by its error details.
+func (p *Pager) renderDetails(entry *pagerEntry) []string {
+	if entry.rec == nil {
+		return nil
+	}
+
+	var buffer bytes.Buffer
+	if len(entry.rec.fields) > 0 {
+		if indented, err := json.MarshalIndent(entry.rec.fields, "", "  "); err == nil {
+			buffer.Write(indented)
+		}
+	}
+
+	if entry.rec.hasErrorDetails() {
+		p.processor.writeErrorDetails(&buffer, entry.rec)
+	}
+
+	text := strings.TrimLeft(buffer.String(), "\n")
+	if text == "" {
+		return []string{Gray(12, "(no fields)").String()}
+	}
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = sanitizePagerLine(line)
+	}
+
+	return lines
+}
+
+func sanitizePagerLine(line string) string {
+	return strings.NewReplacer("\r", "", "\n", "↵", "\t", "    ").Replace(line)
+}
+
+// isVisible reports whether the entry is listed, lines that are not log lines are always
+// listed while records must match the filter, if any, and have a level that is not hidden.
+func (p *Pager) isVisible(entry *pagerEntry) bool {
+	if entry.rec == nil {
+		return true
+	}
+
+	if p.processor.filter != nil && !p.processor.filter.match(entry.rec) {
+		return false
+	}
+
+	return !p.hidden[entry.level]
+}
+
+// refreshVisible recomputes the visible entries after a level toggle, the selection moves
+// to the closest visible entry.
+func (p *Pager) refreshVisible() {
+	current := -1
+	if p.selected < len(p.visible) {
+		current = p.visible[p.selected]
+	}
+
+	p.visible = p.visible[:0]
+	p.selected, p.top = 0, 0
+	for i, entry := range p.entries {
+		if !p.isVisible(entry) {
+			continue
+		}
+
+		if i <= current {
+			p.selected = len(p.visible)
+		}
+
+		p.visible = append(p.visible, i)
+	}
+}
+
+func (p *Pager) handleKey(key string) {
+	p.message = ""
+
+	if p.searching {
+		p.handleSearchKey(key)
+		return
+	}
+
+	switch key {
+	case "q", "\x03":
+		p.quit = true
+	case "j", "\x1b[B", "\x1bOB":
+		p.move(1)
+	case "k", "\x1b[A", "\x1bOA":
+		p.move(-1)
+	case " ", "\x1b[6~", "\x06":
+		p.move(10)
+	case "b", "\x1b[5~", "\x02":
+		p.move(-10)
+	case "g", "\x1b[H", "\x1b[1~", "\x1bOH":
+		p.move(-len(p.visible))
+	case "G", "\x1b[F", "\x1b[4~", "\x1bOF":
+		p.move(len(p.visible))
+	case "\r", "\n":
+		if entry := p.selectedEntry(); entry != nil {
+			entry.expanded = !entry.expanded
+		}
+	case "/":
+		p.searching, p.query, p.searchOrigin = true, "", p.selected
+	case "n":
+		p.searchNext(p.selected+1, 1)
+	case "N":
+		p.searchNext(p.selected-1, -1)
+	case "F":
+		p.follow = !p.follow
+		if p.follow {
+			p.move(len(p.visible))
+		}
+	default:
+		for level, name := range pagerLevels {
+			if key == strings.ToLower(name[:1]) {
+				p.hidden[level] = !p.hidden[level]
+				p.refreshVisible()
+			}
+		}
+	}
+}
+
+func (p *Pager) handleSearchKey(key string) {
+	switch key {
+	case "\r", "\n":
+		p.searching = false
+		return
+	case "\x1b", "\x03":
+		p.searching, p.query, p.selected = false, "", p.searchOrigin
+		return
+	case "\x7f", "\x08":
+		if p.query != "" {
+			_, size := utf8.DecodeLastRuneInString(p.query)
+			p.query = p.query[:len(p.query)-size]
+		}
+	default:
+		if strings.HasPrefix(key, "\x1b") || key < " " {
+			return
+		}
+
+		p.query += key
+	}
+
+	p.selected = p.searchOrigin
+	if p.query != "" {
+		p.searchNext(p.searchOrigin, 1)
+	}
+}
+
+// searchNext selects the first visible entry matching the query starting at `from` in the
+// given direction, wrapping around.
+func (p *Pager) searchNext(from int, direction int) {
+	if p.query == "" || len(p.visible) == 0 {
+		return
+	}
+
+	query := strings.ToLower(p.query)
+	for i := 0; i < len(p.visible); i++ {
+		index := ((from+i*direction)%len(p.visible) + len(p.visible)) % len(p.visible)
+		if strings.Contains(p.entries[p.visible[index]].search, query) {
+			p.selected = index
+			return
+		}
+	}
+
+	p.message = "Pattern not found: " + p.query
+}
+
+func (p *Pager) move(delta int) {
+	p.selected += delta
+	if p.selected >= len(p.visible) {
+		p.selected = len(p.visible) - 1
+	}
+
+	if p.selected < 0 {
+		p.selected = 0
+	}
+
+	// Moving away from the last line stops following the file
+	if delta < 0 {
+		p.follow = false
+	}
+}
+
+func (p *Pager) selectedEntry() *pagerEntry {
+	if p.selected >= len(p.visible) {
+		return nil
+	}
+
+	return p.entries[p.visible[p.selected]]
+}
+
+func (p *Pager) entryRows(entry *pagerEntry) []string {
+	rows := []string{entry.header}
+	if entry.expanded {
+		if entry.details == nil {
+			entry.details = p.renderDetails(entry)
+		}
+
+		for _, detail := range entry.details {
+			rows = append(rows, "    "+detail)
+		}
+	}
+
+	return rows
+}
+
+// render returns the rows of the screen, the list of entries scrolled so that the selected
+// one is shown followed by the status line.
+func (p *Pager) render(width int, height int) []string {
+	listHeight := height - 1
+	if listHeight < 1 {
+		listHeight = 1
+	}
+
+	// Scroll so that the selected entry, with its details when they fit, is on screen
+	if p.selected < p.top {
+		p.top = p.selected
+	}
+
+	for p.top < p.selected {
+		used := 0
+		for i := p.top; i <= p.selected; i++ {
+			used += len(p.entryRows(p.entries[p.visible[i]]))
+		}
+
+		if used <= listHeight {
+			break
+		}
+
+		p.top++
+	}
+
+	rows := make([]string, 0, height)
+	for i := p.top; i < len(p.visible) && len(rows) < listHeight; i++ {
+		for j, row := range p.entryRows(p.entries[p.visible[i]]) {
+			if len(rows) == listHeight {
+				break
+			}
+
+			prefix := "  "
+			if i == p.selected && j == 0 {
+				prefix = Cyan("❯ ").String()
+			}
+
+			rows = append(rows, prefix+truncateANSI(row, width-2))
+		}
+	}
+
+	for len(rows) < listHeight {
+		rows = append(rows, Gray(12, "~").String())
+	}
+
+	return append(rows, p.statusLine(width))
+}
+
+func (p *Pager) statusLine(width int) string {
+	if p.searching {
+		return truncateANSI("/"+p.query, width)
+	}
+
+	position := fmt.Sprintf("%d/%d", p.selected+1, len(p.visible))
+	if len(p.visible) == 0 {
+		position = "0/0"
+	}
+
+	levels := make([]string, len(pagerLevels))
+	for level, name := range pagerLevels {
+		levels[level] = name
+		if p.hidden[level] {
+			levels[level] = "-" + strings.ToLower(name)
+		}
+	}
+
+	parts := []string{position, strings.Join(levels, " ")}
+	if p.follow {
+		parts = append(parts, "following")
+	}
+
+	if p.query != "" {
+		parts = append(parts, "/"+p.query)
+	}
+
+	if p.message != "" {
+		parts = append(parts, p.message)
+	}
+
+	parts = append(parts, "q quit, ⏎ expand, / search, n/N next, d/i/w/e levels, F follow")
+
+	return Inverse(truncateANSI(" "+strings.Join(parts, " │ "), width)).String()
+}
+
+// truncateANSI truncates `text` to `width` visible characters, escape sequences are copied
+// without being counted and the style is reset when the text is cut.
+func truncateANSI(text string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	visible := 0
+	for i := 0; i < len(text); {
+		if text[i] == 0x1b && i+1 < len(text) && text[i+1] == '[' {
+			end := i + 2
+			for end < len(text) && (text[end] < 0x40 || text[end] > 0x7e) {
+				end++
+			}
+
+			if end < len(text) {
+				end++
+			}
+
+			builder.WriteString(text[i:end])
+			i = end
+			continue
+		}
+
+		if visible == width {
+			builder.WriteString("\x1b[0m")
+			break
+		}
+
+		_, size := utf8.DecodeRuneInString(text[i:])
+		builder.WriteString(text[i : i+size])
+		visible++
+		i += size
+	}
+
+	return builder.String()
+}
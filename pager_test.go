@@ -0,0 +1,117 @@
+package zapp
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+func newTestPager(lines ...string) *Pager {
+	pager := NewPager(WithHeaderTemplate(MustNewHeaderTemplate("{{.Level}} {{.Message}}")))
+	for _, line := range lines {
+		pager.appendLine(line)
+	}
+
+	return pager
+}
+
+func renderTestPager(pager *Pager, width int, height int) []string {
+	rows := pager.render(width, height)
+	for i, row := range rows {
+		rows[i] = ansiRegex.ReplaceAllString(row, "")
+	}
+
+	return rows
+}
+
+func TestPager(t *testing.T) {
+	pager := newTestPager(
+		`{"level":"info","ts":1,"msg":"starting","port":8080}`,
+		`{"level":"debug","ts":2,"msg":"tick"}`,
+		`not json`,
+		`{"level":"error","ts":3,"msg":"failed","stacktrace":"main.main\n\t/app/main.go:3"}`,
+		`{"level":"warn","ts":4,"msg":"slow"}`,
+	)
+
+	require.Equal(t, []string{
+		`❯ INFO starting {"port":8080}`,
+		"  DEBUG tick",
+		"  not json",
+		"  ERROR failed",
+		"  WARN slow",
+		" 1/5 │ DEBUG INFO WARN ERROR │ q quit, ⏎",
+	}, renderTestPager(pager, 40, 6), "initial")
+
+	for _, key := range []string{"j", "j", "j", "\r"} {
+		pager.handleKey(key)
+	}
+	require.Equal(t, []string{
+		"  not json",
+		"❯ ERROR failed",
+		"      Stacktrace",
+		"          main.main",
+		"              /app/main.go:3",
+		" 4/5 │ DEBUG INFO WARN ERROR │ q quit, ⏎",
+	}, renderTestPager(pager, 40, 6), "expanded")
+
+	pager.handleKey("d")
+	pager.handleKey("e")
+	require.Equal(t, []string{
+		`  INFO starting {"port":8080}`,
+		"❯ not json",
+		"  WARN slow",
+		"~",
+		"~",
+		" 2/3 │ -debug INFO WARN -error │ q quit,",
+	}, renderTestPager(pager, 40, 6), "levels toggled")
+
+	pager.handleKey("d")
+	pager.handleKey("e")
+	for _, key := range []string{"/", "s", "l"} {
+		pager.handleKey(key)
+	}
+	require.Equal(t, 4, pager.selected, "incremental search")
+	require.Equal(t, "/sl", renderTestPager(pager, 40, 6)[5])
+
+	pager.handleKey("\x7f")
+	pager.handleKey("\r")
+	require.Equal(t, 2, pager.selected, "search from origin after backspace")
+	pager.handleKey("n")
+	require.Equal(t, 4, pager.selected)
+	pager.handleKey("N")
+	require.Equal(t, 2, pager.selected)
+
+	pager.handleKey("F")
+	require.Equal(t, 4, pager.selected)
+	pager.appendLine(`{"level":"info","ts":5,"msg":"new"}`)
+	require.Equal(t, 5, pager.selected, "follows new lines")
+
+	pager.handleKey("q")
+	require.True(t, pager.quit)
+}
+
+func TestPagerNonStringStandardFields(t *testing.T) {
+	pager := newTestPager(
+		`{"level":30,"ts":1,"msg":"numeric level"}`,
+		`{"level":"info","ts":2,"msg":"numeric caller","caller":7}`,
+		`{"level":"info","ts":3,"msg":"valid"}`,
+	)
+
+	require.Equal(t, []string{
+		`❯ {"level":30,"ts":1,"msg":"numeric level"}`,
+		`  {"level":"info","ts":2,"msg":"numeric caller","caller":7}`,
+		"  INFO valid",
+	}, renderTestPager(pager, 80, 4)[:3])
+}
+
+func TestSplitKeys(t *testing.T) {
+	require.Equal(t, []string{"j", "\x1b[A", "\x1b[5~", "é", "\x1b"}, splitKeys([]byte("j\x1b[A\x1b[5~é\x1b")))
+}
+
+func TestTruncateANSI(t *testing.T) {
+	require.Equal(t, "\x1b[31mab\x1b[0m", truncateANSI("\x1b[31mabc\x1b[0m", 2))
+	require.Equal(t, "\x1b[31mabc\x1b[0m", truncateANSI("\x1b[31mabc\x1b[0m", 3))
+}
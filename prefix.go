@@ -0,0 +1,87 @@
+package zapp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// linePrefixPresets are the prefixes added by common tools aggregating logs of several
+// containers, the `source` group is the name rendered as the source tag.
+var linePrefixPresets = map[string]string{
+	// docker compose logs, like `api-1  | {...}`
+	"compose": `^(?P<source>[\w.-]+)\s+\| `,
+	// kubectl logs --prefix, like `[pod/api-7f9c/api] {...}`
+	"kubectl": `^\[(?P<source>[^\]]+)\] `,
+	// stern, like `api-7f9c api {...}`, only split off in front of a JSON object since any
+	// plain text line starting with two words, like `exit status 2`, would match otherwise
+	"stern": `^(?P<source>[a-z0-9][a-z0-9.-]* [a-z0-9][a-z0-9-]*) (?P<rest>\{)`,
+}
+
+// ParseLinePrefix returns the regular expression matching the prefix of lines for one of
+// the presets `compose`, `kubectl` or `stern`, any other value is compiled as a regular
+// expression anchored at the start of the line. The `source` named group is the name of the
+// source, the whole prefix is used when the group is absent. The optional `rest` named group
+// marks where the rest of the line starts when the pattern also matches the beginning of it,
+// like `(?P<rest>\{)` to only split the prefix off in front of a JSON object.
+func ParseLinePrefix(value string) (*regexp.Regexp, error) {
+	if preset, found := linePrefixPresets[value]; found {
+		return regexp.MustCompile(preset), nil
+	}
+
+	expression := value
+	if !strings.HasPrefix(expression, "^") {
+		expression = "^(?:" + expression + ")"
+	}
+
+	pattern, err := regexp.Compile(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid line prefix %q, expected 'compose', 'kubectl', 'stern' or a regular expression: %w", value, err)
+	}
+
+	return pattern, nil
+}
+
+// MustParseLinePrefix is like `ParseLinePrefix` but panics on error.
+func MustParseLinePrefix(value string) *regexp.Regexp {
+	pattern, err := ParseLinePrefix(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return pattern
+}
+
+// WithLinePrefix splits the prefix matched by `pattern` off each line before processing it,
+// the rest of the line is prettified and the prefix is rendered as the source tag. Lines
+// not matching the pattern are processed as is.
+func WithLinePrefix(pattern *regexp.Regexp) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.linePrefix = pattern
+	})
+}
+
+// splitLinePrefix returns the source named by the line prefix and the rest of the line,
+// false if the line has no prefix.
+func (p *Processor) splitLinePrefix(line string) (string, string, bool) {
+	if p.linePrefix == nil {
+		return "", line, false
+	}
+
+	match := p.linePrefix.FindStringSubmatchIndex(line)
+	if match == nil {
+		return "", line, false
+	}
+
+	end := match[1]
+	if group := p.linePrefix.SubexpIndex("rest"); group > 0 && match[2*group] >= 0 {
+		end = match[2*group]
+	}
+
+	source := strings.TrimSpace(line[:end])
+	if group := p.linePrefix.SubexpIndex("source"); group > 0 && match[2*group] >= 0 {
+		source = line[match[2*group]:match[2*group+1]]
+	}
+
+	return source, line[end:], true
+}
@@ -9,10 +9,11 @@ import (
 	"io"
 	"log"
 	"math"
+	"os"
+	"regexp"
 	"strings"
 	"time"
 
-	"github.com/logrusorgru/aurora"
 	. "github.com/logrusorgru/aurora"
 )
 
@@ -43,9 +44,155 @@ func (f ProcessorOptionFunc) apply(p *Processor) {
 	f(p)
 }
 
+// WithDelta shows the delta since the previous line next to the timestamp, it's a shortcut
+// for `WithTimeMode(TimeModeDelta)`.
 func WithDelta(show bool) ProcessorOption {
 	return ProcessorOptionFunc(func(p *Processor) {
-		p.delta = show
+		if show {
+			p.timeMode = TimeModeDelta
+		} else if p.timeMode == TimeModeDelta {
+			p.timeMode = TimeModeNone
+		}
+	})
+}
+
+// WithTimeMode controls the relative time shown next to the timestamp of each line.
+func WithTimeMode(mode TimeMode) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.timeMode = mode
+	})
+}
+
+// WithSlowThresholds highlights gaps between consecutive lines, when the gap exceeds a
+// threshold, the relative time is colored and a separator line is inserted before the
+// line. The first threshold uses a warning color and the second one, if any, a critical one.
+func WithSlowThresholds(thresholds ...time.Duration) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.slowThresholds = thresholds
+	})
+}
+
+// WithOutOfOrderDetection marks lines whose timestamp jumps back in time by more than
+// `tolerance` compared to the previous line and prints a summary count once processing ends.
+func WithOutOfOrderDetection(tolerance time.Duration) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.outOfOrderDetection = true
+		p.outOfOrderTolerance = tolerance
+	})
+}
+
+// WithSummaryOutput sets the writer receiving the summaries printed once processing ends,
+// defaults to standard error.
+func WithSummaryOutput(writer io.Writer) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.summaryOutput = writer
+	})
+}
+
+// WithFilter only prints the log lines matching the filter expression, lines that are
+// not log lines of a supported format are always printed.
+func WithFilter(filter *Filter) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.filter = filter
+	})
+}
+
+// WithContext prints `before` and `after` non-matching log lines around each line matching
+// the filter, like grep does, non-contiguous groups are separated by a `--` line.
+func WithContext(before int, after int) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.contextBefore = before
+		p.contextAfter = after
+		p.contextRing = nil
+
+		if before > 0 {
+			p.contextRing = newContextRing(before)
+		}
+	})
+}
+
+// WithHighlights marks the matches of the highlights in the message and in the field
+// values, the lines are printed whether they match or not.
+func WithHighlights(highlights ...Highlight) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.highlights = append(p.highlights, highlights...)
+	})
+}
+
+// WithDedupe collapses consecutive log lines having the same level, logger and message into
+// a single one followed by `(repeated N times over 12.3s)`, lines of different sources are
+// never collapsed together. When `compareFields` is true, the fields (except the ones whose
+// dotted path matches one of `ignoredFields` glob patterns) must also be equal.
+func WithDedupe(compareFields bool, ignoredFields ...string) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.dedupe = true
+		p.dedupeFields = compareFields
+		p.dedupeIgnoredFields = append(p.dedupeIgnoredFields, ignoredFields...)
+	})
+}
+
+// WithDedupeTimeout prints the line held back by `WithDedupe` once it has been held for
+// this duration even if the repetitions continue, so that live output is not stalled.
+func WithDedupeTimeout(timeout time.Duration) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.dedupeTimeout = timeout
+	})
+}
+
+// WithMaxRate limits the amount of log lines printed per (level, logger, message) key to
+// `count` over each `tick`, like zap's own sampler does, each source being sampled on its
+// own. Dropped lines are reported by periodic `[dropped N lines from logger X]` notices.
+func WithMaxRate(count int, tick time.Duration) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.maxRate = count
+		p.maxRateTick = tick
+	})
+}
+
+// WithDebugSampling only prints one every `n` DEBUG log lines per (level, logger, message)
+// key once the ones allowed by `WithMaxRate` (or the first one if unset) have been printed.
+func WithDebugSampling(n int) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.debugSampling = n
+	})
+}
+
+// WithSummary prints statistics about the input to the summary output once it ends: the
+// counts per level, per logger and per source, the `topMessages` most frequent messages,
+// the amount of lines that are not log lines, the first and last timestamps and the lines
+// per second.
+func WithSummary(topMessages int) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.stats = newSummaryStats()
+		p.summaryTopMessages = topMessages
+	})
+}
+
+// WithSummaryRequests prints the statistics of `WithSummary` gathered so far each time a
+// value is received on `requests`, like on `SIGUSR1`.
+func WithSummaryRequests(requests <-chan struct{}) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.summaryRequests = requests
+	})
+}
+
+// WithStatusBar feeds the statistics shown by the status bar, the processor output should
+// be wrapped by the status bar through `StatusBar.Wrap`. The time of the last error is shown
+// in the time zone of the rendered lines.
+func WithStatusBar(bar *StatusBar) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.statusBar = bar
+		if bar != nil {
+			bar.setLocalizeTime(p.localizeTime)
+		}
+	})
+}
+
+// WithStartMarker resets the reference of `TimeModeSinceStart` each time a line whose
+// message matches the regular expression is seen.
+func WithStartMarker(marker *regexp.Regexp) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.startMarker = marker
 	})
 }
 
@@ -67,6 +214,100 @@ func WithMultilineJSONFieldThreshold(threshold int) ProcessorOption {
 	})
 }
 
+// WithHiddenFields hides the fields whose dotted path matches one of the glob patterns,
+// applies to all formats on top of the format's default hide profile.
+func WithHiddenFields(patterns ...string) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.hiddenFields = append(p.hiddenFields, patterns...)
+	})
+}
+
+// WithOnlyFields only shows the fields whose dotted path matches one of the glob patterns,
+// parent objects of a matching nested field are kept.
+func WithOnlyFields(patterns ...string) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.onlyFields = append(p.onlyFields, patterns...)
+	})
+}
+
+// WithHeaderFields promotes the given fields out of the fields block into the header line,
+// fields without a color get one from a default palette.
+func WithHeaderFields(fields ...HeaderField) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		for _, field := range fields {
+			if field.Color == 0 {
+				field.Color = headerFieldsPalette[len(p.headerFields)%len(headerFieldsPalette)]
+			}
+
+			p.headerFields = append(p.headerFields, field)
+		}
+	})
+}
+
+// WithHeaderTemplate renders the header of each line using the given template instead of
+// the default one, see `NewHeaderTemplate` for details.
+func WithHeaderTemplate(headerTemplate *HeaderTemplate) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.headerTemplate = headerTemplate
+	})
+}
+
+// WithTimeFormat renders timestamps using the given Go time layout or one of the presets
+// returned by `TimeFormatPresets` like `rfc3339`, `time-only`, `kitchen` or `unix-ms`.
+func WithTimeFormat(format string) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		if preset, found := timeFormatPresets[strings.ToLower(format)]; found {
+			format = preset
+		}
+
+		p.timeFormat = format
+	})
+}
+
+// WithTimeZone renders timestamps in the given time zone instead of the local one.
+func WithTimeZone(location *time.Location) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.timeZone = location
+	})
+}
+
+// WithOriginalTimeZone renders timestamps in the time zone the log was written in when
+// the timestamp carries one.
+func WithOriginalTimeZone() ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.originalTimeZone = true
+	})
+}
+
+// WithMaxFieldLength elides string values of the fields that are longer than `length` bytes,
+// a value of 0 disables the truncation.
+func WithMaxFieldLength(length int) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.maxFieldLength = length
+	})
+}
+
+// WithMaxArrayItems elides elements of arrays found in the fields after the first `count`
+// items, a value of 0 disables the truncation.
+func WithMaxArrayItems(count int) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.maxArrayItems = count
+	})
+}
+
+// WithFullFields lists field keys (name or full dotted path) that are never truncated.
+func WithFullFields(keys ...string) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		if p.fullFields == nil {
+			p.fullFields = map[string]bool{}
+		}
+
+		for _, key := range keys {
+			p.fullFields[key] = true
+		}
+	})
+}
+
 func WithDebugLogger(logger *log.Logger) ProcessorOption {
 	return ProcessorOptionFunc(func(p *Processor) {
 		p.debugEnabled = true
@@ -79,21 +320,75 @@ type Processor struct {
 	output  io.Writer
 
 	// State
+	linesWritten           int
+	outputTerminated       bool
+	recordSeq              int
+	lastPrintedSeq         *int
+	contextRing            *contextRing
+	contextAfterRemaining  int
 	lastProcessedTimestamp *time.Time
+	firstTimestamp         *time.Time
+	startTimestamp         *time.Time
+	lastTimestampByLogger  map[string]time.Time
+	outOfOrderCount        int
+	outOfOrderLargestJump  time.Duration
+	dedupeRun              *dedupeRun
+	sampler                *sampler
+	stats                  *summaryStats
+	sourceColors           map[string]Color
+	sourceWidth            int
+	containerPartials      map[containerPartialKey]*containerPartial
+	goTest                 *goTestState
+	goroutineDump          *goroutineDump
 
 	// Options
+	source                      string
+	linePrefix                  *regexp.Regexp
+	showStream                  bool
+	collapseRuntimeGoroutines   bool
 	debugEnabled                bool
 	debugLogger                 *log.Logger
 	multilineJSONFieldThreshold int
 	multilineJSONForced         bool
 	showAllFields               bool
-	delta                       bool
+	hiddenFields                []string
+	onlyFields                  []string
+	headerFields                []HeaderField
+	headerTemplate              *HeaderTemplate
+	timeFormat                  string
+	timeZone                    *time.Location
+	originalTimeZone            bool
+	timeMode                    TimeMode
+	startMarker                 *regexp.Regexp
+	filter                      *Filter
+	contextBefore               int
+	contextAfter                int
+	highlights                  []Highlight
+	dedupe                      bool
+	dedupeFields                bool
+	dedupeIgnoredFields         []string
+	dedupeTimeout               time.Duration
+	maxRate                     int
+	maxRateTick                 time.Duration
+	debugSampling               int
+	slowThresholds              []time.Duration
+	outOfOrderDetection         bool
+	outOfOrderTolerance         time.Duration
+	summaryOutput               io.Writer
+	summaryTopMessages          int
+	summaryRequests             <-chan struct{}
+	statusBar                   *StatusBar
+	maxFieldLength              int
+	maxArrayItems               int
+	fullFields                  map[string]bool
+	stackDepth                  int
 }
 
 func NewProcessor(scanner *bufio.Scanner, output io.Writer, opts ...ProcessorOption) *Processor {
 	processor := &Processor{
-		scanner: scanner,
-		output:  output,
+		scanner:       scanner,
+		output:        output,
+		summaryOutput: os.Stderr,
 
 		debugEnabled:                false,
 		debugLogger:                 nil,
@@ -109,50 +404,166 @@ func NewProcessor(scanner *bufio.Scanner, output io.Writer, opts ...ProcessorOpt
 }
 
 func (p *Processor) Process() {
-	first := true
-	for p.scanner.Scan() {
-		if !first {
-			fmt.Fprintln(p.output)
-		}
+	if (p.dedupe && p.dedupeTimeout > 0) || p.samplingEnabled() || p.summaryRequests != nil {
+		lines := make(chan sourceLine)
+		go func() {
+			defer close(lines)
+			for p.scanner.Scan() {
+				lines <- sourceLine{source: p.source, text: p.scanner.Text()}
+			}
+		}()
 
-		p.processLine(p.scanner.Text())
-		first = false
+		p.processWithEvents(lines)
+	} else {
+		for p.scanner.Scan() {
+			p.processLine(p.source, p.scanner.Text())
+		}
 	}
 
 	if err := p.scanner.Err(); err != nil {
 		p.debugPrintln("Scanner terminated with error: %w", err)
 	}
+
+	p.finish()
+}
+
+// finish flushes the pending output and writes the summaries once the input ends.
+func (p *Processor) finish() {
+	p.flushContainerPartials()
+	p.flushGoTest()
+	p.flushGoroutineDump()
+	p.flushDedupe()
+	p.writeDropNotices()
+	p.writeSummary()
+	p.writeOutOfOrderSummary()
 }
 
-func (p *Processor) processLine(line string) {
+// processWithEvents processes the lines read from separate goroutines so that a line held
+// back by deduplication or pending drop notices can be flushed after their timeout and
+// summary requests can be served even if no new line arrives.
+func (p *Processor) processWithEvents(lines <-chan sourceLine) {
+	for {
+		var timeout <-chan time.Time
+		if deadline, ok := p.nextDeadline(); ok {
+			timeout = time.After(time.Until(deadline))
+		}
+
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return
+			}
+
+			p.processLine(line.source, line.text)
+
+		case <-p.summaryRequests:
+			p.writeSummary()
+
+		case now := <-timeout:
+			if deadline, ok := p.dedupeFlushDeadline(); ok && !now.Before(deadline) {
+				p.flushDedupe()
+			}
+
+			if deadline, ok := p.dropNoticeDeadline(); ok && !now.Before(deadline) {
+				p.writeDropNotices()
+			}
+		}
+	}
+}
+
+// nextDeadline returns the earliest time at which pending output must be flushed, false
+// if there is nothing pending.
+func (p *Processor) nextDeadline() (time.Time, bool) {
+	deadline, found := p.dedupeFlushDeadline()
+	if noticeDeadline, ok := p.dropNoticeDeadline(); ok && (!found || noticeDeadline.Before(deadline)) {
+		deadline, found = noticeDeadline, true
+	}
+
+	return deadline, found
+}
+
+// processLine processes a line read from the named source, empty when there is a single
+// unnamed input.
+func (p *Processor) processLine(source string, line string) {
+	p.debugPrintln("Processing line: %s", line)
+	if prefixSource, rest, found := p.splitLinePrefix(line); found {
+		source, line = prefixSource, rest
+	}
+
+	line, stream, complete := p.unwrapContainerLine(source, line)
+	if !complete {
+		return
+	}
+
+	if p.processGoTestLine(source, line) {
+		return
+	}
+
+	p.processUnwrappedLine(source, stream, line)
+}
+
+// processUnwrappedLine processes a line once its prefix and container envelope, if any, are
+// removed, `stream` is the stream named by the envelope.
+func (p *Processor) processUnwrappedLine(source string, stream string, line string) {
 	defer func() {
 		if err := recover(); err != nil {
-			p.unformattedPrintLine(line, "Panic occurred while processing line '%s', ending processing (%s)", line, err)
+			p.unformattedPrintLine(source, p.streamTagged(stream, line), "Panic occurred while processing line '%s', ending processing (%s)", line, err)
 		}
 	}()
 
-	p.debugPrintln("Processing line: %s", line)
+	if p.consumeGoroutineDumpLine(source, stream, line) {
+		p.stats.countNonJSON()
+		p.statusBar.countNonRecord()
+		return
+	}
+
+	lineData, err := decodeJSONLine(line)
+	if err != nil {
+		p.unformattedPrintLine(source, p.streamTagged(stream, line), "%s, ending processing", err)
+		return
+	}
+
+	rec, err := p.parseRecord(lineData)
+	if err != nil {
+		p.stats.countUnparseable()
+		p.statusBar.countNonRecord()
+		p.notPrettyPrintedLine(source, p.streamTagged(stream, line), err)
+		return
+	}
+
+	rec.source = source
+	rec.stream = stream
+	p.processRecord(line, rec)
+}
+
+// processRecord accounts for the parsed record and prints it if it's selected.
+func (p *Processor) processRecord(line string, rec *record) {
+	p.stats.countRecord(rec)
+	p.statusBar.countRecord(rec)
+	p.handleRecord(line, rec)
+}
+
+// decodeJSONLine decodes the line as a JSON object, keeping the order of the keys isn't
+// required but the whole line must be a single object.
+func decodeJSONLine(line string) (map[string]interface{}, error) {
 	reader := bytes.NewReader([]byte(line))
 	decoder := json.NewDecoder(reader)
 
 	token, err := decoder.Token()
 	if err != nil {
-		p.unformattedPrintLine(line, "Does not look like a JSON line, ending processing (%s)", err)
-		return
+		return nil, fmt.Errorf("does not look like a JSON line (%w)", err)
 	}
 
 	delim, ok := token.(json.Delim)
 	if !ok || delim != '{' {
-		p.unformattedPrintLine(line, "Expecting a JSON object delimited, ending processing")
-		return
+		return nil, fmt.Errorf("expecting a JSON object delimited")
 	}
 
 	lineData := map[string]interface{}{}
 	for decoder.More() {
 		token, err := decoder.Token()
 		if err != nil {
-			p.unformattedPrintLine(line, "Invalid JSON key in line, ending processing (%s)", err)
-			return
+			return nil, fmt.Errorf("invalid JSON key in line (%w)", err)
 		}
 
 		key := token.(string)
@@ -164,8 +575,7 @@ func (p *Processor) processLine(line string) {
 
 		var value interface{}
 		if err := decoder.Decode(&value); err != nil {
-			p.unformattedPrintLine(line, "Invalid JSON value in line, ending processing (%s)", err)
-			return
+			return nil, fmt.Errorf("invalid JSON value in line (%w)", err)
 		}
 
 		lineData[key] = value
@@ -173,59 +583,112 @@ func (p *Processor) processLine(line string) {
 
 	// Read the ending delimiter of the JSON object
 	if _, err := decoder.Token(); err != nil {
-		p.unformattedPrintLine(line, "Invalid JSON, misssing object end delimiter in line, ending processing (%s)", err)
-		return
+		return nil, fmt.Errorf("invalid JSON, misssing object end delimiter in line (%w)", err)
 	}
 
-	prettyLine, err := p.maybePrettyPrintLine(line, lineData)
+	return lineData, nil
+}
 
-	if err != nil {
-		fmt.Fprint(p.output, line)
+func (p *Processor) notPrettyPrintedLine(source string, line string, err error) {
+	p.flushDedupe()
+	p.writeSourceLine(source, line)
 
-		switch err {
-		case errNonZapLine:
-			p.debugPrintln("Not a known zap line format")
-		default:
-			p.debugPrintln("Not printing line due to error: %s", err)
-		}
-	} else {
-		fmt.Fprint(p.output, prettyLine)
+	switch err {
+	case errNonZapLine:
+		p.debugPrintln("Not a known zap line format")
+	default:
+		p.debugPrintln("Not printing line due to error: %s", err)
+	}
+}
+
+// writeLine writes text to the output, lines are separated by a newline but the last one
+// is not terminated.
+func (p *Processor) writeLine(text string) {
+	if p.linesWritten > 0 && !p.outputTerminated {
+		io.WriteString(p.output, "\n")
+	}
+	p.outputTerminated = false
+
+	io.WriteString(p.output, text)
+	p.linesWritten++
+}
+
+// terminateOutput ends the last written line, used before writing summaries so that they
+// don't end up glued to the output when both streams share the same terminal.
+func (p *Processor) terminateOutput() {
+	if p.linesWritten > 0 && !p.outputTerminated {
+		io.WriteString(p.output, "\n")
+		p.outputTerminated = true
 	}
 }
 
-func (p *Processor) maybePrettyPrintLine(line string, lineData map[string]interface{}) (string, error) {
+// record is a log line of one of the supported formats once parsed, the standard keys
+// are extracted from the JSON object and the remaining ones are kept in `fields`.
+type record struct {
+	timestamp *time.Time
+	severity  string
+	caller    *string
+	logger    *string
+	message   string
+
+	fields        map[string]interface{}
+	stacktrace    string
+	errorVerboses []errorVerbose
+	errorCauses   []errorCause
+
+	// errorJoined is true when the error causes are the lines of the `error` field
+	errorJoined bool
+
+	// hiddenFields is the default hide profile of the format the record was parsed from
+	hiddenFields []string
+
+	// source is the name of the input the record comes from, empty when there is a single one
+	source string
+
+	// stream is the stream named by the container log envelope the record was unwrapped from
+	stream string
+}
+
+// hasErrorDetails reports whether the record has details about an error rendered below
+// its header.
+func (rec *record) hasErrorDetails() bool {
+	return len(rec.errorVerboses) > 0 || rec.stacktrace != "" || len(rec.errorCauses) > 0
+}
+
+func (p *Processor) parseRecord(lineData map[string]interface{}) (*record, error) {
 	if lineData["level"] != nil && lineData["ts"] != nil && lineData["msg"] != nil {
-		return p.maybePrettyPrintZapLine(line, lineData)
+		return p.parseZapRecord(lineData)
 	}
 
 	if lineData["severity"] != nil && (lineData["time"] != nil || lineData["timestamp"] != nil) && lineData["message"] != nil {
-		return p.maybePrettyPrintZapdriverLine(line, lineData)
+		return p.parseZapdriverRecord(lineData)
 	}
 
-	return "", errNonZapLine
+	return nil, errNonZapLine
 }
 
-func (p *Processor) maybePrettyPrintZapLine(line string, lineData map[string]interface{}) (string, error) {
+func (p *Processor) parseZapRecord(lineData map[string]interface{}) (*record, error) {
 	logTimestamp, err := tsFieldToTimestamp(lineData["ts"])
 	if err != nil {
-		return "", fmt.Errorf("unable to process field 'ts': %w", err)
+		return nil, fmt.Errorf("unable to process field 'ts': %w", err)
 	}
 
-	var caller *string
-	if v := lineData["caller"]; v != nil {
-		callerStr := v.(string)
-		caller = &callerStr
+	severity, severityOk := lineData["level"].(string)
+	message, messageOk := lineData["msg"].(string)
+	caller, callerOk := optionalStringField(lineData, "caller")
+	logger, loggerOk := optionalStringField(lineData, "logger")
+	if !severityOk || !messageOk || !callerOk || !loggerOk {
+		return nil, errNonZapLine
 	}
 
-	var logger *string
-	if v := lineData["logger"]; v != nil {
-		loggerStr := v.(string)
-		logger = &loggerStr
+	rec := &record{
+		timestamp: logTimestamp,
+		severity:  severity,
+		caller:    caller,
+		logger:    logger,
+		message:   message,
 	}
 
-	var buffer bytes.Buffer
-	p.writeHeader(&buffer, logTimestamp, lineData["level"].(string), caller, logger, lineData["msg"].(string))
-
 	// Delete standard stuff from data fields
 	delete(lineData, "level")
 	delete(lineData, "ts")
@@ -233,19 +696,16 @@ func (p *Processor) maybePrettyPrintZapLine(line string, lineData map[string]int
 	delete(lineData, "logger")
 	delete(lineData, "msg")
 
-	stacktrace := ""
 	if t, ok := lineData["stacktrace"].(string); ok && t != "" {
 		delete(lineData, "stacktrace")
-		stacktrace = t
+		rec.stacktrace = t
 	}
 
-	p.writeJSON(&buffer, lineData)
+	rec.errorVerboses = extractErrorVerboses(lineData)
+	rec.errorCauses, rec.errorJoined = extractErrorCauses(lineData)
+	rec.fields = lineData
 
-	if stacktrace != "" {
-		p.writeErrorDetails(&buffer, "", stacktrace)
-	}
-
-	return buffer.String(), nil
+	return rec, nil
 }
 
 var zeroTime = time.Time{}
@@ -261,7 +721,6 @@ func tsFieldToTimestamp(input interface{}) (*time.Time, error) {
 
 	case string:
 		timestamp, err := time.Parse(time.RFC3339Nano, v)
-		timestamp = timestamp.Local()
 
 		return &timestamp, err
 	}
@@ -269,7 +728,7 @@ func tsFieldToTimestamp(input interface{}) (*time.Time, error) {
 	return &zeroTime, fmt.Errorf("don't know how to turn %T (value %s) into a time.Time object", input, input)
 }
 
-func (p *Processor) maybePrettyPrintZapdriverLine(line string, lineData map[string]interface{}) (string, error) {
+func (p *Processor) parseZapdriverRecord(lineData map[string]interface{}) (*record, error) {
 	timeField := "time"
 	timeValue := lineData[timeField]
 	if lineData[timeField] == nil {
@@ -277,27 +736,28 @@ func (p *Processor) maybePrettyPrintZapdriverLine(line string, lineData map[stri
 		timeValue = lineData[timeField]
 	}
 
-	var buffer bytes.Buffer
-
 	parsedTime, err := tsFieldToTimestamp(timeValue)
 	if err != nil {
-		return "", fmt.Errorf("unable to process field %q: %w", timeField, err)
+		return nil, fmt.Errorf("unable to process field %q: %w", timeField, err)
 	}
 
-	var caller *string
-	if v := lineData["caller"]; v != nil {
-		callerStr := v.(string)
-		caller = &callerStr
+	severity, severityOk := lineData["severity"].(string)
+	message, messageOk := lineData["message"].(string)
+	caller, callerOk := optionalStringField(lineData, "caller")
+	logger, loggerOk := optionalStringField(lineData, "logger")
+	if !severityOk || !messageOk || !callerOk || !loggerOk {
+		return nil, errNonZapLine
 	}
 
-	var logger *string
-	if v := lineData["logger"]; v != nil {
-		loggerStr := v.(string)
-		logger = &loggerStr
+	rec := &record{
+		timestamp:    parsedTime,
+		severity:     severity,
+		caller:       caller,
+		logger:       logger,
+		message:      message,
+		hiddenFields: zapdriverHiddenFields,
 	}
 
-	p.writeHeader(&buffer, parsedTime, lineData["severity"].(string), caller, logger, lineData["message"].(string))
-
 	// Delete standard stuff from data fields
 	delete(lineData, timeField)
 	delete(lineData, "severity")
@@ -305,100 +765,172 @@ func (p *Processor) maybePrettyPrintZapdriverLine(line string, lineData map[stri
 	delete(lineData, "logger")
 	delete(lineData, "message")
 
-	if !p.showAllFields {
-		delete(lineData, "labels")
-		delete(lineData, "serviceContext")
-		delete(lineData, "logging.googleapis.com/labels")
-		delete(lineData, "logging.googleapis.com/sourceLocation")
+	if t, ok := lineData["stacktrace"].(string); ok && t != "" {
+		delete(lineData, "stacktrace")
+		rec.stacktrace = t
 	}
 
-	errorVerbose := ""
-	if t, ok := lineData["errorVerbose"].(string); ok && t != "" {
-		delete(lineData, "errorVerbose")
-		errorVerbose = t
+	rec.errorVerboses = extractErrorVerboses(lineData)
+	rec.errorCauses, rec.errorJoined = extractErrorCauses(lineData)
+	rec.fields = lineData
+
+	return rec, nil
+}
+
+// optionalStringField returns the value of the field if present, false if it's present but
+// not a string.
+func optionalStringField(lineData map[string]interface{}, key string) (*string, bool) {
+	v := lineData[key]
+	if v == nil {
+		return nil, true
 	}
 
-	stacktrace := ""
-	if t, ok := lineData["stacktrace"].(string); ok && t != "" {
-		delete(lineData, "stacktrace")
-		stacktrace = t
+	value, ok := v.(string)
+	if !ok {
+		return nil, false
 	}
 
-	p.writeJSON(&buffer, lineData)
+	return &value, true
+}
+
+func (p *Processor) renderRecord(rec *record) (string, error) {
+	headerFields, fields := p.extractHeaderFields(rec.renderedFields())
+
+	var buffer bytes.Buffer
+	if err := p.writeHeader(&buffer, rec, headerFields); err != nil {
+		return "", fmt.Errorf("unable to render header: %w", err)
+	}
+	p.writeJSON(&buffer, p.selectFields(fields, rec.hiddenFields))
 
-	if errorVerbose != "" || stacktrace != "" {
-		p.writeErrorDetails(&buffer, errorVerbose, stacktrace)
+	if rec.hasErrorDetails() {
+		p.writeErrorDetails(&buffer, rec)
 	}
 
 	return buffer.String(), nil
 }
 
-const timeFormat = "2006-01-02 15:04:05.000 MST"
+func (p *Processor) writeHeader(buffer *bytes.Buffer, rec *record, headerFields []headerFieldValue) error {
+	timestamp := rec.timestamp
 
-func (p *Processor) writeHeader(buffer *bytes.Buffer, timestamp *time.Time, severity string, caller *string, logger *string, message string) {
-	defer func() {
-		if p.lastProcessedTimestamp == nil {
-			p.lastProcessedTimestamp = timestamp
-			return
-		}
+	// Out-of-order lines are not tracked so that the next lines are compared against the
+	// most recent timestamp instead of the one that jumped back in time.
+	jump, outOfOrder := p.checkOutOfOrder(rec)
+	if !outOfOrder {
+		defer p.trackTimestamp(rec)
+	}
 
-		if timestamp != nil {
-			p.lastProcessedTimestamp = timestamp
-		}
-	}()
+	data := &HeaderData{
+		Relative: p.relativeTime(rec),
+		Level:    strings.ToUpper(rec.severity),
+		Message:  p.highlightMessage(rec.message),
+	}
+
+	if outOfOrder {
+		data.OutOfOrder = outOfOrderMarker(jump)
+	}
+
+	if timestamp != nil {
+		data.Time = p.localizeTime(*timestamp)
+		data.Timestamp = p.formatTime(data.Time)
 
-	if p.delta {
-		delta := "-"
-		if p.lastProcessedTimestamp != nil && timestamp != nil {
-			delta = durationToString(timestamp.Sub(*p.lastProcessedTimestamp))
+		if p.lastProcessedTimestamp != nil {
+			delta := timestamp.Sub(*p.lastProcessedTimestamp)
+			data.Delta = &delta
+
+			if color := p.slowGapColor(delta); color != 0 {
+				writeSlowGapSeparator(buffer, delta, color)
+
+				if data.Relative != "" {
+					data.Relative = Colorize(data.Relative, color).String()
+				}
+			}
 		}
+	}
 
-		buffer.WriteString(fmt.Sprintf("[%s, %s]", timestamp.Format(timeFormat), delta))
-	} else {
-		buffer.WriteString(fmt.Sprintf("[%s]", timestamp.Format(timeFormat)))
+	var origin []string
+	if rec.logger != nil {
+		data.Logger = *rec.logger
+		origin = append(origin, data.Logger)
 	}
 
-	buffer.WriteByte(' ')
-	buffer.WriteString(p.colorizeSeverity(severity).String())
+	if rec.caller != nil {
+		data.Caller = *rec.caller
+		origin = append(origin, data.Caller)
+	}
 
-	if logger != nil && caller != nil {
-		buffer.WriteByte(' ')
-		buffer.WriteString(Gray(12, fmt.Sprintf("(%s, %s)", *logger, *caller)).String())
-	} else if logger != nil {
-		buffer.WriteByte(' ')
-		buffer.WriteString(Gray(12, fmt.Sprintf("(%s)", *logger)).String())
-	} else if caller != nil {
-		buffer.WriteByte(' ')
-		buffer.WriteString(Gray(12, fmt.Sprintf("(%s)", *caller)).String())
+	data.Origin = strings.Join(origin, ", ")
+
+	if rec.source != "" {
+		data.Source = p.sourceTag(rec.source)
+	}
+
+	if p.showStream {
+		data.Stream = rec.stream
+	}
+
+	if len(headerFields) > 0 {
+		var fieldsBuffer bytes.Buffer
+		writeHeaderFields(&fieldsBuffer, headerFields)
+		data.HeaderFields = fieldsBuffer.String()
+	}
+
+	headerTemplate := p.headerTemplate
+	if headerTemplate == nil {
+		headerTemplate = defaultHeaderTemplate
 	}
 
-	buffer.WriteByte(' ')
-	buffer.WriteString(Blue(message).String())
+	return headerTemplate.execute(buffer, data)
 }
 
 var temporaryStackSpacer = "_-@\\!/@-_"
 
-func (p *Processor) writeErrorDetails(buffer *bytes.Buffer, errorVerbose string, stacktrace string) {
-	if stacktrace != "" {
-		buffer.WriteByte('\n')
-		buffer.WriteString("Stacktrace\n")
-		buffer.WriteString("    " + strings.ReplaceAll(stacktrace, "\n", "\n    "))
+func (p *Processor) writeErrorDetails(buffer *bytes.Buffer, rec *record) {
+	// Each block starts on its own line, an extra empty line separates it from the previous one
+	written := false
+	separate := func() {
+		if written {
+			buffer.WriteByte('\n')
+		}
+		written = true
 	}
 
-	if stacktrace != "" && errorVerbose != "" {
-		// If both are present, stacktrace has print something, so let's add an extra empty line here for spacing
+	if len(rec.errorCauses) > 0 {
+		separate()
+		writeErrorCauses(buffer, rec.errorCauses)
+	}
+
+	if rec.stacktrace != "" {
+		separate()
 		buffer.WriteByte('\n')
+		buffer.WriteString("Stacktrace\n")
+		p.writeStacktrace(buffer, rec.stacktrace)
 	}
 
 	// The `errorVerbose` seems to contain a stack trace for each error captured. This behavior
 	// comes from `github.com/pkg/errors` that create a stack of errors, each of the item having an associate
 	// stacktrace.
-	if errorVerbose != "" {
-		writeErrorVerbose(buffer, errorVerbose)
+	for _, verbose := range rec.errorVerboses {
+		separate()
+		writeErrorVerbose(buffer, verbose)
 	}
 }
 
-func writeErrorVerbose(buffer *bytes.Buffer, errorVerbose string) {
+// writeErrorVerbose writes the Error Verbose block, labelled by the key of the error field
+// unless it's the default `error` one.
+func writeErrorVerbose(buffer *bytes.Buffer, verbose errorVerbose) {
+	buffer.WriteByte('\n')
+	if verbose.key == "error" {
+		buffer.WriteString("Error Verbose\n")
+	} else {
+		buffer.WriteString("Error Verbose (" + verbose.key + ")\n")
+	}
+
+	writeErrorVerboseBody(buffer, verbose.text)
+}
+
+// writeErrorVerboseBody writes the errors of the `errorVerbose` value each followed by its
+// stack trace, without the block title.
+func writeErrorVerboseBody(buffer *bytes.Buffer, errorVerbose string) {
 	joinedErrorVerbose := strings.ReplaceAll(errorVerbose, "\n\t", temporaryStackSpacer)
 	scanner := bufio.NewScanner(strings.NewReader("  " + joinedErrorVerbose))
 
@@ -406,8 +938,6 @@ func writeErrorVerbose(buffer *bytes.Buffer, errorVerbose string) {
 	var lineCurrent *string
 	startedSection := false
 
-	buffer.WriteByte('\n')
-	buffer.WriteString("Error Verbose\n")
 	for scanner.Scan() {
 		if lineCurrent != nil {
 			linePrevious = lineCurrent
@@ -480,6 +1010,8 @@ func (p *Processor) writeJSON(buffer *bytes.Buffer, data map[string]interface{})
 	//        big. But what represents a too big value exactly? We would need to serialize to
 	//        JSON, check length, if smaller than threshold, print with space, otherwise
 	//        re-serialize with pretty-printing stuff
+	data = p.highlightFields(p.truncateFields(data))
+
 	var jsonBytes []byte
 	var err error
 
@@ -494,22 +1026,25 @@ func (p *Processor) writeJSON(buffer *bytes.Buffer, data map[string]interface{})
 		p.debugPrintln("Unable to marshal data as JSON: %s", err)
 	} else {
 		buffer.WriteByte(' ')
-		buffer.Write(jsonBytes)
+		buffer.Write(applyHighlightMarkers(jsonBytes))
 	}
 }
 
-func (p *Processor) colorizeSeverity(severity string) aurora.Value {
+func severityColor(severity string) Color {
 	color := severityToColor[strings.ToLower(severity)]
 	if color == 0 {
 		color = BlueFg
 	}
 
-	return Colorize(strings.ToUpper(severity), color)
+	return color
 }
 
-func (p *Processor) unformattedPrintLine(line string, message string, args ...interface{}) {
+func (p *Processor) unformattedPrintLine(source string, line string, message string, args ...interface{}) {
 	p.debugPrintln(message, args...)
-	fmt.Fprint(p.output, line)
+	p.stats.countNonJSON()
+	p.statusBar.countNonRecord()
+	p.flushDedupe()
+	p.writeSourceLine(source, line)
 }
 
 func (p *Processor) debugPrintln(msg string, args ...interface{}) {
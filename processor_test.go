@@ -1,10 +1,17 @@
 package zapp
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
 
+	. "github.com/logrusorgru/aurora"
 	"github.com/stretchr/testify/require"
 )
 
@@ -249,6 +256,1266 @@ func TestZapDriverNewProduction(t *testing.T) {
 	})
 }
 
+func TestFieldTruncation(t *testing.T) {
+	runLogTests(t, []logTest{
+		{
+			name: "long_string",
+			lines: []string{
+				`{"level":"info","ts":1545445711.144533,"msg":"m","data":"0x123456789abcdef"}`,
+			},
+			expectedLines: []string{
+				"[2018-12-21 21:28:31.144 EST] \x1b[32mINFO\x1b[0m \x1b[34mm\x1b[0m {\"data\":\"0x1234…(+11 bytes)\"}",
+			},
+			options: []ProcessorOption{WithMaxFieldLength(6)},
+		},
+		{
+			name: "long_string_multi_bytes_boundary",
+			lines: []string{
+				`{"level":"info","ts":1545445711.144533,"msg":"m","data":"aéééé"}`,
+			},
+			expectedLines: []string{
+				"[2018-12-21 21:28:31.144 EST] \x1b[32mINFO\x1b[0m \x1b[34mm\x1b[0m {\"data\":\"aé…(+6 bytes)\"}",
+			},
+			options: []ProcessorOption{WithMaxFieldLength(4)},
+		},
+		{
+			name: "long_array_nested",
+			lines: []string{
+				`{"level":"info","ts":1545445711.144533,"msg":"m","req":{"ids":[1,2,3,4,5]}}`,
+			},
+			expectedLines: []string{
+				"[2018-12-21 21:28:31.144 EST] \x1b[32mINFO\x1b[0m \x1b[34mm\x1b[0m {\"req\":{\"ids\":[1,2,\"...3 more\"]}}",
+			},
+			options: []ProcessorOption{WithMaxArrayItems(2)},
+		},
+		{
+			name: "full_fields_by_name_and_path",
+			lines: []string{
+				`{"level":"info","ts":1545445711.144533,"msg":"m","a":"abcdef","b":"abcdef","req":{"c":"abcdef"}}`,
+			},
+			expectedLines: []string{
+				"[2018-12-21 21:28:31.144 EST] \x1b[32mINFO\x1b[0m \x1b[34mm\x1b[0m {\"a\":\"abcdef\",\"b\":\"ab…(+4 bytes)\",\"req\":{\"c\":\"abcdef\"}}",
+			},
+			options: []ProcessorOption{WithMaxFieldLength(2), WithFullFields("a", "req.c")},
+		},
+	})
+}
+
+func TestStacktrace(t *testing.T) {
+	stacktrace := strings.Join([]string{
+		"github.com/acme/api.(*Server).fetch",
+		"\t/app/server.go:42",
+		"github.com/acme/api.(*Server).ServeHTTP",
+		"\t/app/server.go:20",
+		"net/http.serverHandler.ServeHTTP",
+		"\t/usr/local/go/src/net/http/server.go:3142",
+		"net/http.(*conn).serve",
+		"\t/usr/local/go/src/net/http/server.go:2044",
+		"go.uber.org/zap.(*Logger).Error",
+		"\t/root/go/pkg/mod/go.uber.org/zap@v1.27.0/logger.go:254",
+		"runtime.goexit",
+		"\t/usr/local/go/src/runtime/asm_amd64.s:1700",
+	}, "\n")
+
+	line, _ := json.Marshal(map[string]interface{}{"level": "error", "ts": 1545445711, "msg": "m", "stacktrace": stacktrace})
+	header := "m"
+
+	runLogTests(t, []logTest{
+		{
+			name:  "folded",
+			lines: []string{string(line)},
+			expectedLines: []string{
+				header,
+				"Stacktrace",
+				"    " + Cyan("github.com/acme/api.(*Server).fetch").String(),
+				"    \t/app/server.go:" + Yellow("42").String(),
+				"    " + Cyan("github.com/acme/api.(*Server).ServeHTTP").String(),
+				"    \t/app/server.go:" + Yellow("20").String(),
+				"    " + Gray(12, "… 2 frames in net/http").String(),
+				"    " + Gray(12, "go.uber.org/zap.(*Logger).Error").String(),
+				"    \t" + Gray(12, "/root/go/pkg/mod/go.uber.org/zap@v1.27.0/logger.go:254").String(),
+				"    " + Gray(12, "runtime.goexit").String(),
+				"    \t" + Gray(12, "/usr/local/go/src/runtime/asm_amd64.s:1700").String(),
+			},
+			options: []ProcessorOption{WithHeaderTemplate(MustNewHeaderTemplate("{{.Message}}"))},
+		},
+		{
+			name:  "depth",
+			lines: []string{string(line)},
+			expectedLines: []string{
+				header,
+				"Stacktrace",
+				"    " + Cyan("github.com/acme/api.(*Server).fetch").String(),
+				"    \t/app/server.go:" + Yellow("42").String(),
+				"    " + Gray(12, "… 5 more frames").String(),
+			},
+			options: []ProcessorOption{WithStackDepth(1), WithHeaderTemplate(MustNewHeaderTemplate("{{.Message}}"))},
+		},
+		{
+			name:  "depth counts folded frames once",
+			lines: []string{string(line)},
+			expectedLines: []string{
+				header,
+				"Stacktrace",
+				"    " + Cyan("github.com/acme/api.(*Server).fetch").String(),
+				"    \t/app/server.go:" + Yellow("42").String(),
+				"    " + Cyan("github.com/acme/api.(*Server).ServeHTTP").String(),
+				"    \t/app/server.go:" + Yellow("20").String(),
+				"    " + Gray(12, "… 2 frames in net/http").String(),
+				"    " + Gray(12, "go.uber.org/zap.(*Logger).Error").String(),
+				"    \t" + Gray(12, "/root/go/pkg/mod/go.uber.org/zap@v1.27.0/logger.go:254").String(),
+				"    " + Gray(12, "… 1 more frame").String(),
+			},
+			options: []ProcessorOption{WithStackDepth(4), WithHeaderTemplate(MustNewHeaderTemplate("{{.Message}}"))},
+		},
+		{
+			name:  "module without dot",
+			lines: []string{`{"level":"error","ts":1545445711,"msg":"m","stacktrace":"myapp/internal/db.Query\n\t/home/me/myapp/internal/db/db.go:12\nruntime.goexit\n\t/usr/local/go/src/runtime/asm_amd64.s:1700"}`},
+			expectedLines: []string{
+				header,
+				"Stacktrace",
+				"    " + Cyan("myapp/internal/db.Query").String(),
+				"    \t/home/me/myapp/internal/db/db.go:" + Yellow("12").String(),
+				"    " + Gray(12, "runtime.goexit").String(),
+				"    \t" + Gray(12, "/usr/local/go/src/runtime/asm_amd64.s:1700").String(),
+			},
+			options: []ProcessorOption{WithHeaderTemplate(MustNewHeaderTemplate("{{.Message}}"))},
+		},
+	})
+}
+
+func TestErrorCauses(t *testing.T) {
+	options := []ProcessorOption{WithHeaderTemplate(MustNewHeaderTemplate("{{.Message}}"))}
+
+	runLogTests(t, []logTest{
+		{
+			name: "multierr",
+			lines: []string{
+				`{"level":"error","ts":1545445711,"msg":"m","error":"a; b; c","errorCauses":[{"error":"a"},{"error":"b","errorVerbose":"b\nmain.main\n\t/app/main.go:12"},{"error":"c","errorCauses":[{"error":"c1"},{"error":"c2\nc3"}]}]}`,
+			},
+			expectedLines: []string{
+				`m {"error":"a; b; c"}`,
+				"Error Causes",
+				"  - a",
+				"  - b",
+				"    b",
+				"    main.main",
+				"    \t/app/main.go:12",
+				"  - c",
+				"    - c1",
+				"    - c2",
+				"      c3",
+			},
+			options: options,
+		},
+		{
+			name: "errors.Join",
+			lines: []string{
+				`{"level":"error","ts":1545445711,"msg":"m","error":"open a: denied\ndial b: refused","key":"v"}`,
+			},
+			expectedLines: []string{
+				`m {"key":"v"}`,
+				"Error Causes",
+				"  - open a: denied",
+				"  - dial b: refused",
+			},
+			options: options,
+		},
+		{
+			name: "errors.Join filtered",
+			lines: []string{
+				`{"level":"error","ts":1545445711,"msg":"m","error":"open a: denied\ndial b: refused"}`,
+				`{"level":"error","ts":1545445711,"msg":"n","error":"open a: denied\ndial c: refused"}`,
+			},
+			expectedLines: []string{
+				"m",
+				"Error Causes",
+				"  - open a: denied",
+				"  - dial b: refused",
+			},
+			options: append([]ProcessorOption{WithFilter(MustParseFilter(`fields.error =~ "dial b"`))}, options...),
+		},
+		{
+			name: "with stacktrace",
+			lines: []string{
+				`{"level":"error","ts":1545445711,"msg":"m","errorCauses":[{"error":"a"}],"stacktrace":"Stack1a\n\tFile1a"}`,
+			},
+			expectedLines: []string{
+				"m",
+				"Error Causes",
+				"  - a",
+				"",
+				"Stacktrace",
+				"    Stack1a",
+				"    \tFile1a",
+			},
+			options: options,
+		},
+		{
+			name: "not causes",
+			lines: []string{
+				`{"level":"error","ts":1545445711,"msg":"m","errorCauses":"a"}`,
+			},
+			expectedLines: []string{
+				`m {"errorCauses":"a"}`,
+			},
+			options: options,
+		},
+	})
+}
+
+func TestErrorVerbose(t *testing.T) {
+	options := []ProcessorOption{WithHeaderTemplate(MustNewHeaderTemplate("{{.Message}}"))}
+
+	runLogTests(t, []logTest{
+		{
+			name: "zap format",
+			lines: []string{
+				`{"level":"error","ts":1545445711,"msg":"m","error":"e","errorVerbose":"e\nmain.main\n\t/app/main.go:12"}`,
+			},
+			expectedLines: []string{
+				`m {"error":"e"}`,
+				"Error Verbose",
+				"",
+				"    e",
+				"    main.main",
+				"    \t/app/main.go:12",
+			},
+			options: options,
+		},
+		{
+			name: "named errors",
+			lines: []string{
+				`{"level":"error","ts":1545445711,"msg":"m","error":"e","errorVerbose":"e verbose","cause":"c","causeVerbose":"c verbose","isVerbose":"yes"}`,
+			},
+			expectedLines: []string{
+				`m {"cause":"c","error":"e","isVerbose":"yes"}`,
+				"Error Verbose",
+				"  e verbose",
+				"",
+				"Error Verbose (cause)",
+				"  c verbose",
+			},
+			options: options,
+		},
+	})
+}
+
+func TestFieldSelection(t *testing.T) {
+	header := "[2018-12-21 21:28:31.144 EST] \x1b[32mINFO\x1b[0m \x1b[34mm\x1b[0m "
+	line := `{"level":"info","ts":1545445711.144533,"msg":"m","span_id":"s","req":{"id":1,"headers":{"host":"h","agent":"a"}}}`
+
+	runLogTests(t, []logTest{
+		{
+			name:          "hide_nested_glob",
+			lines:         []string{line},
+			expectedLines: []string{header + `{"req":{"headers":{},"id":1},"span_id":"s"}`},
+			options:       []ProcessorOption{WithHiddenFields("req.headers.*")},
+		},
+		{
+			name:          "hide_top_level_glob",
+			lines:         []string{line},
+			expectedLines: []string{header + `{"req":{"headers":{"agent":"a","host":"h"},"id":1}}`},
+			options:       []ProcessorOption{WithHiddenFields("span_*")},
+		},
+		{
+			name:          "only_nested_keeps_parents",
+			lines:         []string{line},
+			expectedLines: []string{header + `{"req":{"headers":{"host":"h"},"id":1}}`},
+			options:       []ProcessorOption{WithOnlyFields("req.id", "req.*.host")},
+		},
+		{
+			name:          "only_with_hidden_child",
+			lines:         []string{line},
+			expectedLines: []string{header + `{"req":{"headers":{"agent":"a"},"id":1}}`},
+			options:       []ProcessorOption{WithOnlyFields("req"), WithHiddenFields("**.host")},
+		},
+		{
+			name:          "only_no_match",
+			lines:         []string{line},
+			expectedLines: []string{strings.TrimSuffix(header, " ")},
+			options:       []ProcessorOption{WithOnlyFields("unknown")},
+		},
+		{
+			name:          "zapdriver_profile_with_hidden",
+			lines:         []string{zapdriverLine("INFO", "2018-12-21T23:06:49.435919-05:00")},
+			expectedLines: []string{"[2018-12-21 23:06:49.435 EST] \x1b[32mINFO\x1b[0m \x1b[38;5;244m(c:0)\x1b[0m \x1b[34mm\x1b[0m"},
+			options:       []ProcessorOption{WithHiddenFields("folder")},
+		},
+		{
+			name:          "zapdriver_profile_disabled_by_all",
+			lines:         []string{zapdriverLine("INFO", "2018-12-21T23:06:49.435919-05:00")},
+			expectedLines: []string{"[2018-12-21 23:06:49.435 EST] \x1b[32mINFO\x1b[0m \x1b[38;5;244m(c:0)\x1b[0m \x1b[34mm\x1b[0m {\"labels\":{}}"},
+			options:       []ProcessorOption{WithAllFields(), WithHiddenFields("folder", "logging.googleapis.com/*")},
+		},
+	})
+}
+
+func TestFilter(t *testing.T) {
+	runLogTests(t, []logTest{
+		{
+			name: "keeps_non_log_lines",
+			lines: []string{
+				`{"level":"info","ts":1545445711.144533,"logger":"p2p","msg":"a","peer_count":2}`,
+				`{"level":"info","ts":1545445711.144533,"logger":"p2p","msg":"b","peer_count":5}`,
+				"A non-JSON string line",
+				`{"level":"info","ts":1545445711.144533,"logger":"db","msg":"c"}`,
+				`{"level":"info","ts":1545445711.144533,"logger":"p2p","msg":"d","peer_count":1}`,
+			},
+			expectedLines: []string{
+				`a {"peer_count":2}`,
+				"A non-JSON string line",
+				`d {"peer_count":1}`,
+			},
+			options: []ProcessorOption{
+				WithFilter(MustParseFilter(`logger == "p2p" && fields.peer_count < 3`)),
+				WithHeaderTemplate(MustNewHeaderTemplate("{{.Message}}")),
+			},
+		},
+	})
+}
+
+func TestFilterContext(t *testing.T) {
+	var lines []string
+	for _, message := range []string{"a", "b", "c", "M1", "d", "e", "f", "g", "M2", "M3", "h", "i"} {
+		lines = append(lines, fmt.Sprintf(`{"level":"info","ts":1545445711.144533,"msg":"%s"}`, message))
+	}
+
+	context := func(name string, before, after int, expected ...string) logTest {
+		return logTest{
+			name:          name,
+			lines:         lines,
+			expectedLines: expected,
+			options: []ProcessorOption{
+				WithFilter(MustParseFilter(`msg =~ "^M"`)),
+				WithContext(before, after),
+				WithHeaderTemplate(MustNewHeaderTemplate("{{.Message}}")),
+			},
+		}
+	}
+
+	separator := "\x1b[38;5;244m--\x1b[0m"
+
+	runLogTests(t, []logTest{
+		context("none", 0, 0, "M1", "M2", "M3"),
+		context("before", 2, 0, "b", "c", "M1", separator, "f", "g", "M2", "M3"),
+		context("after", 0, 1, "M1", "d", separator, "M2", "M3", "h"),
+		context("around_contiguous", 3, 3, "a", "b", "c", "M1", "d", "e", "f", "g", "M2", "M3", "h", "i"),
+	})
+}
+
+func TestHighlight(t *testing.T) {
+	runLogTests(t, []logTest{
+		{
+			name: "message_and_fields",
+			lines: []string{
+				`{"level":"info","ts":1545445711.144533,"msg":"peer timeout, timeout","peer":"timeout\"x","n":1}`,
+			},
+			expectedLines: []string{
+				"[2018-12-21 21:28:31.144 EST] \x1b[32mINFO\x1b[0m \x1b[34mpeer \x1b[1;7mtimeout\x1b[22;27m, \x1b[1;7mtimeout\x1b[22;27m\x1b[0m {\"n\":1,\"peer\":\"\x1b[1;7mtimeout\x1b[22;27m\\\"x\"}",
+			},
+			options: []ProcessorOption{WithHighlights(MustParseHighlight("time(out)?"))},
+		},
+		{
+			name: "overlapping_matches_are_merged",
+			lines: []string{
+				`{"level":"info","ts":1545445711.144533,"msg":"abcdef"}`,
+			},
+			expectedLines: []string{
+				"[2018-12-21 21:28:31.144 EST] \x1b[32mINFO\x1b[0m \x1b[34ma\x1b[1;7mbcde\x1b[22;27mf\x1b[0m",
+			},
+			options: []ProcessorOption{WithHighlights(MustParseHighlight("bcd"), MustParseHighlight("cde"))},
+		},
+		{
+			name: "scoped_to_field",
+			lines: []string{
+				`{"level":"info","ts":1545445711.144533,"msg":"/api","req":{"path":"/api/x"},"other":"/api"}`,
+			},
+			expectedLines: []string{
+				"[2018-12-21 21:28:31.144 EST] \x1b[32mINFO\x1b[0m \x1b[34m/api\x1b[0m {\"other\":\"/api\",\"req\":{\"path\":\"\x1b[1;7m/api\x1b[22;27m/x\"}}",
+			},
+			options: []ProcessorOption{WithHighlights(MustParseHighlight("req.*=~^/api"))},
+		},
+		{
+			name: "scoped_to_message",
+			lines: []string{
+				`{"level":"info","ts":1545445711.144533,"msg":"error","err":"error"}`,
+			},
+			expectedLines: []string{
+				"[2018-12-21 21:28:31.144 EST] \x1b[32mINFO\x1b[0m \x1b[34m\x1b[1;7merror\x1b[22;27m\x1b[0m {\"err\":\"error\"}",
+			},
+			options: []ProcessorOption{WithHighlights(MustParseHighlight("msg=~err"), MustParseHighlight("msg=~or"))},
+		},
+		{
+			name: "scoped_to_message_not_fields_of_same_name",
+			lines: []string{
+				`{"level":"info","ts":1545445711.144533,"msg":"m","message":"error"}`,
+			},
+			expectedLines: []string{
+				"[2018-12-21 21:28:31.144 EST] \x1b[32mINFO\x1b[0m \x1b[34mm\x1b[0m {\"message\":\"error\"}",
+			},
+			options: []ProcessorOption{WithHighlights(MustParseHighlight("message=~err"))},
+		},
+		{
+			name: "numbers_and_booleans",
+			lines: []string{
+				`{"level":"info","ts":1545445711.144533,"msg":"block 308","block_num":308,"count":3080,"tags":[308,"308"]}`,
+			},
+			expectedLines: []string{
+				"[2018-12-21 21:28:31.144 EST] \x1b[32mINFO\x1b[0m \x1b[34mblock \x1b[1;7m308\x1b[22;27m\x1b[0m {\"block_num\":\x1b[1;7m308\x1b[22;27m,\"count\":\x1b[1;7m308\x1b[22;27m0,\"tags\":[\x1b[1;7m308\x1b[22;27m,\"\x1b[1;7m308\x1b[22;27m\"]}",
+			},
+			options: []ProcessorOption{WithHighlights(MustParseHighlight("308"))},
+		},
+		{
+			name: "boolean_scoped_to_field",
+			lines: []string{
+				`{"level":"info","ts":1545445711.144533,"msg":"m","ok":true,"done":true}`,
+			},
+			expectedLines: []string{
+				"[2018-12-21 21:28:31.144 EST] \x1b[32mINFO\x1b[0m \x1b[34mm\x1b[0m {\"done\":true,\"ok\":\x1b[1;7mtrue\x1b[22;27m}",
+			},
+			options: []ProcessorOption{WithHighlights(MustParseHighlight("ok=~true"))},
+		},
+	})
+}
+
+func TestDedupe(t *testing.T) {
+	tmpl := WithHeaderTemplate(MustNewHeaderTemplate("{{.Relative}} {{.Level}} {{.Message}}"))
+	repeated := func(text string) string { return " " + Gray(12, text).String() }
+
+	runLogTests(t, []logTest{
+		{
+			name: "collapses_consecutive",
+			lines: []string{
+				`{"level":"warn","ts":"2018-12-21T21:28:31.000Z","logger":"l","msg":"retrying","attempt":1}`,
+				`{"level":"warn","ts":"2018-12-21T21:28:32.000Z","logger":"l","msg":"retrying","attempt":2}`,
+				`{"level":"warn","ts":"2018-12-21T21:28:33.500Z","logger":"l","msg":"retrying","attempt":3}`,
+				`{"level":"warn","ts":"2018-12-21T21:28:34.000Z","logger":"other","msg":"retrying"}`,
+				`{"level":"info","ts":"2018-12-21T21:28:35.000Z","msg":"done"}`,
+				`{"level":"info","ts":"2018-12-21T21:28:36.000Z","msg":"done"}`,
+				`not json`,
+				`{"level":"info","ts":"2018-12-21T21:28:37.000Z","msg":"done"}`,
+			},
+			expectedLines: []string{
+				"- WARN retrying {\"attempt\":1}" + repeated("(repeated 3 times over 2.5s)"),
+				"500ms WARN retrying",
+				"1s INFO done" + repeated("(repeated 2 times over 1s)"),
+				"not json",
+				"1s INFO done",
+			},
+			options: []ProcessorOption{WithDelta(true), tmpl, WithDedupe(false)},
+		},
+		{
+			name: "compare_fields",
+			lines: []string{
+				`{"level":"warn","ts":"2018-12-21T21:28:31.000Z","msg":"retrying","peer":"a","attempt":1}`,
+				`{"level":"warn","ts":"2018-12-21T21:28:32.000Z","msg":"retrying","peer":"a","attempt":2}`,
+				`{"level":"warn","ts":"2018-12-21T21:28:33.000Z","msg":"retrying","peer":"b","attempt":3}`,
+			},
+			expectedLines: []string{
+				" WARN retrying {\"attempt\":1,\"peer\":\"a\"}" + repeated("(repeated 2 times over 1s)"),
+				" WARN retrying {\"attempt\":3,\"peer\":\"b\"}",
+			},
+			options: []ProcessorOption{tmpl, WithDedupe(true, "attempt")},
+		},
+	})
+}
+
+func TestDedupeTimeout(t *testing.T) {
+	reader, input := io.Pipe()
+	writer := &bytes.Buffer{}
+
+	processor := &Processor{scanner: bufio.NewScanner(reader), output: writer, multilineJSONFieldThreshold: 3}
+	WithHeaderTemplate(MustNewHeaderTemplate("{{.Message}}")).apply(processor)
+	WithDedupe(false).apply(processor)
+	WithDedupeTimeout(20 * time.Millisecond).apply(processor)
+
+	go func() {
+		io.WriteString(input, `{"level":"info","ts":1,"msg":"a"}`+"\n"+`{"level":"info","ts":2,"msg":"a"}`+"\n")
+		time.Sleep(200 * time.Millisecond)
+		io.WriteString(input, `{"level":"info","ts":3,"msg":"a"}`+"\n")
+		input.Close()
+	}()
+
+	processor.Process()
+
+	require.Equal(t, "a "+Gray(12, "(repeated 2 times over 1s)").String()+"\na", writer.String())
+}
+
+func TestSampling(t *testing.T) {
+	tmpl := WithHeaderTemplate(MustNewHeaderTemplate("{{.Level}} {{.Message}}"))
+	dropped := func(text string) string { return Gray(12, text).String() }
+
+	runLogTests(t, []logTest{
+		{
+			name: "max_rate",
+			lines: []string{
+				`{"level":"info","ts":"2018-12-21T21:28:31.000Z","logger":"net","msg":"flood"}`,
+				`{"level":"info","ts":"2018-12-21T21:28:31.100Z","logger":"net","msg":"flood"}`,
+				`{"level":"info","ts":"2018-12-21T21:28:31.200Z","logger":"net","msg":"flood"}`,
+				`{"level":"info","ts":"2018-12-21T21:28:31.300Z","logger":"net","msg":"other"}`,
+				`{"level":"info","ts":"2018-12-21T21:28:31.400Z","logger":"net","msg":"flood"}`,
+				`{"level":"info","ts":"2018-12-21T21:28:32.000Z","logger":"net","msg":"flood"}`,
+				`{"level":"info","ts":"2018-12-21T21:28:32.100Z","logger":"db","msg":"flood"}`,
+				`{"level":"info","ts":"2018-12-21T21:28:32.200Z","msg":"flood"}`,
+				`{"level":"info","ts":"2018-12-21T21:28:32.300Z","msg":"flood"}`,
+				`{"level":"info","ts":"2018-12-21T21:28:32.400Z","msg":"flood"}`,
+			},
+			expectedLines: []string{
+				"INFO flood",
+				"INFO flood",
+				"INFO other",
+				"INFO flood",
+				"INFO flood",
+				dropped("[dropped 2 lines from logger net]"),
+				"INFO flood",
+				"INFO flood",
+				dropped("[dropped 1 lines]"),
+			},
+			options: []ProcessorOption{tmpl, WithMaxRate(2, time.Second)},
+		},
+		{
+			name: "debug_sampling",
+			lines: []string{
+				`{"level":"debug","ts":"2018-12-21T21:28:31.000Z","msg":"q"}`,
+				`{"level":"debug","ts":"2018-12-21T21:28:31.000Z","msg":"q"}`,
+				`{"level":"info","ts":"2018-12-21T21:28:31.000Z","msg":"q"}`,
+				`{"level":"debug","ts":"2018-12-21T21:28:31.000Z","msg":"q"}`,
+				`{"level":"debug","ts":"2018-12-21T21:28:31.000Z","msg":"q"}`,
+				`{"level":"info","ts":"2018-12-21T21:28:31.000Z","msg":"q"}`,
+				`{"level":"debug","ts":"2018-12-21T21:28:32.000Z","msg":"q"}`,
+			},
+			expectedLines: []string{
+				"DEBUG q",
+				"INFO q",
+				"DEBUG q",
+				"INFO q",
+				dropped("[dropped 2 lines]"),
+				"DEBUG q",
+			},
+			options: []ProcessorOption{tmpl, WithDebugSampling(3)},
+		},
+	})
+}
+
+func TestParseSampling(t *testing.T) {
+	count, tick, err := ParseMaxRate("100/s")
+	require.NoError(t, err)
+	require.Equal(t, 100, count)
+	require.Equal(t, time.Second, tick)
+
+	_, tick, err = ParseMaxRate("5/500ms")
+	require.NoError(t, err)
+	require.Equal(t, 500*time.Millisecond, tick)
+
+	for _, value := range []string{"100", "0/s", "a/s", "1/x", "1/-1s"} {
+		_, _, err = ParseMaxRate(value)
+		require.Error(t, err, value)
+	}
+
+	n, err := ParseSampling("1/100")
+	require.NoError(t, err)
+	require.Equal(t, 100, n)
+
+	for _, value := range []string{"100", "2/100", "1/0"} {
+		_, err = ParseSampling(value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestParseHighlight(t *testing.T) {
+	highlight, err := ParseHighlight("req.path=~^/api")
+	require.NoError(t, err)
+	require.Equal(t, "req.path", highlight.Field)
+	require.Equal(t, "^/api", highlight.Pattern.String())
+
+	highlight, err = ParseHighlight("a (b=~c)")
+	require.NoError(t, err)
+	require.Equal(t, "", highlight.Field)
+	require.Equal(t, "a (b=~c)", highlight.Pattern.String())
+
+	_, err = ParseHighlight("msg=~")
+	require.Error(t, err)
+
+	_, err = ParseHighlight("(")
+	require.Error(t, err)
+}
+
+func TestHeaderFields(t *testing.T) {
+	runLogTests(t, []logTest{
+		{
+			name: "promoted_with_label_and_nested",
+			lines: []string{
+				`{"level":"info","ts":1545445711.144533,"logger":"l","msg":"m","request_id":"abc","block":{"num":308267722},"other":1}`,
+			},
+			expectedLines: []string{
+				"[2018-12-21 21:28:31.144 EST] \x1b[32mINFO\x1b[0m \x1b[38;5;244m(l)\x1b[0m \x1b[38;5;244m[\x1b[0m\x1b[36mreq=abc\x1b[0m \x1b[33mblock.num=308267722\x1b[0m\x1b[38;5;244m]\x1b[0m \x1b[34mm\x1b[0m {\"other\":1}",
+			},
+			options: []ProcessorOption{WithHeaderFields(
+				HeaderField{Key: "request_id", Label: "req"},
+				HeaderField{Key: "missing"},
+				HeaderField{Key: "block.num", Color: YellowFg},
+			)},
+		},
+	})
+}
+
+func TestParseHeaderField(t *testing.T) {
+	field, err := ParseHeaderField("request_id=req:yellow")
+	require.NoError(t, err)
+	require.Equal(t, HeaderField{Key: "request_id", Label: "req", Color: YellowFg}, field)
+
+	field, err = ParseHeaderField("block_num")
+	require.NoError(t, err)
+	require.Equal(t, HeaderField{Key: "block_num"}, field)
+
+	_, err = ParseHeaderField("block_num:unknown")
+	require.Error(t, err)
+}
+
+func TestHeaderTemplate(t *testing.T) {
+	runLogTests(t, []logTest{
+		{
+			name: "custom_format",
+			lines: []string{
+				`{"level":"info","ts":1545445711.144533,"logger":"l","msg":"m","a":1}`,
+				`{"level":"warn","ts":1545445712.144533,"msg":"a much longer message"}`,
+			},
+			expectedLines: []string{
+				`INFO  21:28:31.144 l: m {"a":1}`,
+				`WARN  21:28:32.144 : a much…`,
+			},
+			options: []ProcessorOption{WithHeaderTemplate(MustNewHeaderTemplate(`{{.Level | pad 5}} {{.Time | fmt "15:04:05.000"}} {{.Logger}}: {{.Message | truncate 7}}`))},
+		},
+		{
+			name: "helpers",
+			lines: []string{
+				`{"level":"info","ts":1545445711.144533,"msg":"m"}`,
+				`{"level":"info","ts":1545445712.144533,"msg":"m"}`,
+			},
+			expectedLines: []string{
+				"[\x1b[32m INFO\x1b[0m] - \x1b[31mM\x1b[0m",
+				"[\x1b[32m INFO\x1b[0m] 1s \x1b[31mM\x1b[0m",
+			},
+			options: []ProcessorOption{WithHeaderTemplate(MustNewHeaderTemplate(`[{{.Level | pad -5 | levelColor}}] {{.Delta | delta}} {{.Message | upper | color "red"}}`))},
+		},
+		{
+			name: "execution_error_prints_raw_line",
+			lines: []string{
+				`{"level":"info","ts":1545445711.144533,"msg":"m"}`,
+			},
+			expectedLines: []string{
+				`{"level":"info","ts":1545445711.144533,"msg":"m"}`,
+			},
+			options: []ProcessorOption{WithHeaderTemplate(MustNewHeaderTemplate(`{{if eq .Message "m"}}{{.Message | color "unknown"}}{{end}}`))},
+		},
+	})
+}
+
+func TestNewHeaderTemplate(t *testing.T) {
+	_, err := NewHeaderTemplate(`{{.Foo}}`)
+	require.ErrorContains(t, err, "can't evaluate field Foo")
+
+	_, err = NewHeaderTemplate(`{{.Message | color "unknown"}}`)
+	require.Error(t, err)
+
+	_, err = NewHeaderTemplate(`{{.Delta | delta}} {{.Time | fmt "15:04"}} {{.Level | pad 5 | levelColor}}`)
+	require.NoError(t, err)
+}
+
+func TestTimeFormatAndZone(t *testing.T) {
+	utc, _ := time.LoadLocation("UTC")
+	paris, _ := time.LoadLocation("Europe/Paris")
+	header := " \x1b[32mINFO\x1b[0m \x1b[34mm\x1b[0m"
+	stringLine := `{"level":"info","ts":"2019-12-06T19:40:20.627+09:00","msg":"m"}`
+	numericLine := `{"level":"info","ts":1545445711.144533,"msg":"m"}`
+
+	runLogTests(t, []logTest{
+		{
+			name:          "preset",
+			lines:         []string{numericLine},
+			expectedLines: []string{"[21:28:31.144]" + header},
+			options:       []ProcessorOption{WithTimeFormat("time-only")},
+		},
+		{
+			name:          "unix_ms",
+			lines:         []string{numericLine},
+			expectedLines: []string{"[1545445711144]" + header},
+			options:       []ProcessorOption{WithTimeFormat("unix-ms")},
+		},
+		{
+			name:          "layout_and_utc",
+			lines:         []string{stringLine},
+			expectedLines: []string{"[2019/12/06 10:40 UTC]" + header},
+			options:       []ProcessorOption{WithTimeFormat("2006/01/02 15:04 MST"), WithTimeZone(utc)},
+		},
+		{
+			name:          "iana",
+			lines:         []string{numericLine},
+			expectedLines: []string{"[2018-12-22T03:28:31+01:00]" + header},
+			options:       []ProcessorOption{WithTimeFormat("rfc3339"), WithTimeZone(paris)},
+		},
+		{
+			name:          "original",
+			lines:         []string{stringLine, numericLine},
+			expectedLines: []string{"[2019-12-06T19:40:20.627+09:00]" + header, "[2018-12-21T21:28:31.144-05:00]" + header},
+			options:       []ProcessorOption{WithTimeFormat("rfc3339-ms"), WithOriginalTimeZone()},
+		},
+	})
+}
+
+func TestTimeModes(t *testing.T) {
+	lines := []string{
+		`{"level":"info","ts":1545445711.0,"logger":"a","msg":"boot"}`,
+		`{"level":"info","ts":1545445713.0,"logger":"b","msg":"starting"}`,
+		"A non-JSON string line",
+		`{"level":"info","ts":1545445716.0,"logger":"a","msg":"ready"}`,
+	}
+
+	relatives := func(mode TimeMode, expected ...string) logTest {
+		return logTest{
+			name:          mode.String(),
+			lines:         lines,
+			expectedLines: expected,
+			options: []ProcessorOption{
+				WithTimeMode(mode),
+				WithStartMarker(regexp.MustCompile("^start")),
+				WithHeaderTemplate(MustNewHeaderTemplate("{{.Relative}}")),
+			},
+		}
+	}
+
+	runLogTests(t, []logTest{
+		relatives(TimeModeNone, "", "", "A non-JSON string line", ""),
+		relatives(TimeModeDelta, "-", "2s", "A non-JSON string line", "3s"),
+		relatives(TimeModeElapsed, "+0s", "+2s", "A non-JSON string line", "+5s"),
+		relatives(TimeModeSinceStart, "+0s", "+0s", "A non-JSON string line", "+3s"),
+		relatives(TimeModeLoggerDelta, "-", "-", "A non-JSON string line", "5s"),
+	})
+}
+
+func TestSlowGaps(t *testing.T) {
+	runLogTests(t, []logTest{
+		{
+			name: "tiers",
+			lines: []string{
+				`{"level":"info","ts":"2018-12-21T21:28:31.000Z","msg":"a"}`,
+				`{"level":"info","ts":"2018-12-21T21:28:31.100Z","msg":"b"}`,
+				`{"level":"info","ts":"2018-12-21T21:28:32.000Z","msg":"c"}`,
+				`{"level":"info","ts":"2018-12-21T21:28:37.000Z","msg":"d"}`,
+			},
+			expectedLines: []string{
+				"- a",
+				"100ms b",
+				"\x1b[33m──── 900ms gap ────\x1b[0m",
+				"\x1b[33m900ms\x1b[0m c",
+				"\x1b[31m──── 5s gap ────\x1b[0m",
+				"\x1b[31m5s\x1b[0m d",
+			},
+			options: []ProcessorOption{
+				WithDelta(true),
+				WithSlowThresholds(500*time.Millisecond, 5*time.Second),
+				WithHeaderTemplate(MustNewHeaderTemplate("{{.Relative}} {{.Message}}")),
+			},
+		},
+	})
+}
+
+func TestOutOfOrder(t *testing.T) {
+	lines := []string{
+		`{"level":"info","ts":"2018-12-21T21:28:31.000Z","msg":"a"}`,
+		`{"level":"info","ts":"2018-12-21T21:28:32.000Z","msg":"b"}`,
+		`{"level":"info","ts":"2018-12-21T21:28:31.995Z","msg":"c"}`,
+		`{"level":"info","ts":"2018-12-21T21:28:30.000Z","msg":"d"}`,
+		`{"level":"info","ts":"2018-12-21T21:28:33.000Z","msg":"e"}`,
+	}
+
+	summary := &bytes.Buffer{}
+	writer := executeProcessorTest(lines,
+		WithDelta(true),
+		WithOutOfOrderDetection(10*time.Millisecond),
+		WithSummaryOutput(summary),
+		WithHeaderTemplate(MustNewHeaderTemplate("{{.Relative}}{{with .OutOfOrder}} {{.}}{{end}} {{.Message}}")),
+	)
+
+	require.Equal(t, []string{
+		"- a",
+		"1s b",
+		"-5ms c",
+		"-1.995s \x1b[1;35m↶ -1.995s\x1b[0m d",
+		"1.005s e",
+		"",
+	}, strings.Split(writer.String(), "\n"))
+	require.Equal(t, "Detected 1 out-of-order line(s) (tolerance 10ms, largest backwards jump 1.995s)\n", summary.String())
+}
+
+func TestSummary(t *testing.T) {
+	lines := []string{
+		`{"level":"info","ts":"2018-12-21T21:28:31.000Z","logger":"net","msg":"a"}`,
+		`{"level":"error","ts":"2018-12-21T21:28:32.000Z","msg":"b"}`,
+		`not json`,
+		`{"level":"info","ts":"2018-12-21T21:28:35.000Z","logger":"net","msg":"a"}`,
+		`{"other":"format"}`,
+		`{"level":"debug","ts":"2018-12-21T21:28:33.000Z","logger":"db","msg":"c"}`,
+	}
+
+	summary := &bytes.Buffer{}
+	writer := executeProcessorTest(lines,
+		WithFilter(MustParseFilter(`level == "error"`)),
+		WithSummary(2),
+		WithSummaryOutput(summary),
+		WithHeaderTemplate(MustNewHeaderTemplate("{{.Message}}")),
+	)
+
+	require.Equal(t, "b\nnot json\n{\"other\":\"format\"}\n", writer.String())
+	require.Equal(t, strings.Join([]string{
+		"Summary",
+		"  Lines        6 (4 log lines, 1 non-JSON, 1 unparseable)",
+		"  First        2018-12-21 16:28:31.000 EST",
+		"  Last         2018-12-21 16:28:35.000 EST (4s)",
+		"  Rate         1.5 lines/s",
+		"  Levels       ERROR 1, INFO 2, DEBUG 1",
+		"  Loggers      net 2, <none> 1, db 1",
+		"  Top messages",
+		"    2  a",
+		"    1  b",
+		"",
+	}, "\n"), summary.String())
+}
+
+func TestSource(t *testing.T) {
+	runLogTests(t, []logTest{
+		{
+			name: "tagged",
+			lines: []string{
+				`{"level":"info","ts":1545445711.144533,"caller":"c","msg":"m"}`,
+				`not json`,
+			},
+			expectedLines: []string{
+				BrightBlue("api |").String() + " [2018-12-21 21:28:31.144 EST] " + Green("INFO").String() + " " + Gray(12, "(c)").String() + " " + Blue("m").String(),
+				BrightBlue("api |").String() + " not json",
+			},
+			options: []ProcessorOption{WithSource("api")},
+		},
+		{
+			name: "filter",
+			lines: []string{
+				`{"level":"info","ts":1545445711.144533,"msg":"m1"}`,
+			},
+			expectedLines: []string{""},
+			options: []ProcessorOption{
+				WithSource("api"),
+				WithFilter(MustParseFilter(`source == "worker"`)),
+			},
+		},
+	})
+}
+
+func TestLinePrefix(t *testing.T) {
+	header := WithHeaderTemplate(MustNewHeaderTemplate("{{.Source}} {{.Message}}"))
+
+	runLogTests(t, []logTest{
+		{
+			name: "compose",
+			lines: []string{
+				`api-1     | {"level":"info","ts":1545445711,"msg":"m1"}`,
+				`worker-1  | panic: boom`,
+				`not prefixed`,
+			},
+			expectedLines: []string{
+				BrightYellow("api-1 |").String() + " m1",
+				BrightMagenta("worker-1 |").String() + " " + Colorize("panic: boom", RedFg|BoldFm).String(),
+				"not prefixed",
+			},
+			options: []ProcessorOption{header, WithLinePrefix(MustParseLinePrefix("compose"))},
+		},
+		{
+			name: "kubectl",
+			lines: []string{
+				`[pod/api-7f9c/api] {"level":"info","ts":1545445711,"msg":"m1"}`,
+			},
+			expectedLines: []string{
+				BrightMagenta("pod/api-7f9c/api |").String() + " m1",
+			},
+			options: []ProcessorOption{header, WithLinePrefix(MustParseLinePrefix("kubectl"))},
+		},
+		{
+			name: "stern",
+			lines: []string{
+				`api-7f9c api {"level":"info","ts":1545445711,"msg":"m1"}`,
+				`{"level": "info", "ts": 1545445711, "msg": "m2"}`,
+				`hello world`,
+				`exit status 2`,
+				`panic: runtime error: index out of range`,
+				``,
+				`goroutine 1 [running]:`,
+			},
+			expectedLines: []string{
+				BrightGreen("api-7f9c api |").String() + " m1",
+				" m2",
+				"hello world",
+				"exit status 2",
+				Colorize("panic: runtime error: index out of range", RedFg|BoldFm).String(),
+				"",
+				Bold("goroutine 1").String() + " " + Yellow("[running]").String() + ":",
+			},
+			options: []ProcessorOption{header, WithLinePrefix(MustParseLinePrefix("stern"))},
+		},
+		{
+			name: "regex",
+			lines: []string{
+				`api: {"level":"info","ts":1545445711,"msg":"m1"}`,
+				`worker: {"level":"info","ts":1545445711,"msg":"m2"}`,
+			},
+			expectedLines: []string{
+				BrightBlue("api |").String() + " m1",
+			},
+			options: []ProcessorOption{
+				header,
+				WithLinePrefix(MustParseLinePrefix(`(?P<source>\w+): `)),
+				WithFilter(MustParseFilter(`source == "api"`)),
+			},
+		},
+	})
+}
+
+func TestParseLinePrefix(t *testing.T) {
+	prefix, err := ParseLinePrefix(`\w+ -> `)
+	require.NoError(t, err)
+	require.Equal(t, `^(?:\w+ -> )`, prefix.String())
+
+	_, err = ParseLinePrefix(`(`)
+	require.Error(t, err)
+}
+
+func TestStream(t *testing.T) {
+	source := func(name string, lines ...string) Source {
+		return Source{Name: name, Scanner: bufio.NewScanner(strings.NewReader(strings.Join(lines, "\n")))}
+	}
+
+	writer := &bytes.Buffer{}
+	summary := &bytes.Buffer{}
+	processor := &Processor{output: writer, multilineJSONFieldThreshold: 3}
+	for _, opt := range []ProcessorOption{
+		WithHeaderTemplate(MustNewHeaderTemplate("{{.Source}} {{.Message}}")),
+		WithFilter(MustParseFilter(`source == "worker" || msg == "a2"`)),
+		WithSummary(0),
+		WithSummaryOutput(summary),
+	} {
+		opt.apply(processor)
+	}
+
+	processor.Stream(
+		source("api", `{"level":"info","ts":1545445711,"msg":"a1"}`, `{"level":"info","ts":1545445712,"msg":"a2"}`),
+		source("worker", `{"level":"info","ts":1545445711,"msg":"w1"}`, `panic: boom`),
+	)
+
+	apiTag, workerTag := BrightBlue("api    |").String(), BrightYellow("worker |").String()
+
+	// Sources are read concurrently, only the order of the lines of a same source is known
+	lines := strings.Split(strings.TrimSuffix(writer.String(), "\n"), "\n")
+	panicLine := workerTag + " " + Colorize("panic: boom", RedFg|BoldFm).String()
+	require.ElementsMatch(t, []string{apiTag + " a2", workerTag + " w1", panicLine}, lines)
+	require.Less(t, indexOf(lines, workerTag+" w1"), indexOf(lines, panicLine))
+	require.Contains(t, summary.String(), "  Sources      api 2, worker 1\n")
+}
+
+func indexOf(values []string, value string) int {
+	for i, candidate := range values {
+		if candidate == value {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func TestContainerLogs(t *testing.T) {
+	header := WithHeaderTemplate(MustNewHeaderTemplate("{{.Stream}}|{{.Message}}"))
+
+	runLogTests(t, []logTest{
+		{
+			name: "docker",
+			lines: []string{
+				`{"log":"{\"level\":\"info\",\"ts\":1545445711,\"msg\":\"m1\"}\n","stream":"stderr","time":"2018-12-22T02:28:31.000Z"}`,
+				`{"log":"panic: boom\n","stream":"stderr","time":"2018-12-22T02:28:32.000Z"}`,
+				`{"log":"{\"level\":\"info\",\"ts\":1545445711,","stream":"stdout","time":"2018-12-22T02:28:33.000Z"}`,
+				`{"log":"\"msg\":\"m2\"}\r\n","stream":"stdout","time":"2018-12-22T02:28:33.000Z"}`,
+			},
+			expectedLines: []string{
+				"|m1",
+				Colorize("panic: boom", RedFg|BoldFm).String(),
+				"|m2",
+			},
+			options: []ProcessorOption{header},
+		},
+		{
+			name: "cri",
+			lines: []string{
+				`2018-12-22T02:28:31.000000000Z stderr F {"level":"info","ts":1545445711,"msg":"m1"}`,
+				`2018-12-22T02:28:32.000000000Z stdout P {"level":"info",`,
+				`2018-12-22T02:28:32.000000000Z stdout P "ts":1545445711,`,
+				`2018-12-22T02:28:32.000000000Z stdout F "msg":"m2"}`,
+				`2018-12-22T02:28:33.000000000Z stderr F panic: boom`,
+			},
+			expectedLines: []string{
+				"stderr|m1",
+				"stdout|m2",
+				Gray(12, "stderr").String() + " " + Colorize("panic: boom", RedFg|BoldFm).String(),
+			},
+			options: []ProcessorOption{header, WithStreamName()},
+		},
+		{
+			name: "filter",
+			lines: []string{
+				`2018-12-22T02:28:31.000000000Z stderr F {"level":"info","ts":1545445711,"msg":"m1"}`,
+				`2018-12-22T02:28:31.000000000Z stdout F {"level":"info","ts":1545445711,"msg":"m2"}`,
+			},
+			expectedLines: []string{
+				"|m2",
+			},
+			options: []ProcessorOption{header, WithFilter(MustParseFilter(`stream == "stdout"`))},
+		},
+		{
+			name: "interleaved streams",
+			lines: []string{
+				`2018-12-22T02:28:32.000000000Z stdout P {"level":"info",`,
+				`2018-12-22T02:28:32.000000000Z stderr P {"level":"warn",`,
+				`2018-12-22T02:28:32.000000000Z stdout F "ts":1545445711,"msg":"out"}`,
+				`2018-12-22T02:28:32.000000000Z stderr F "ts":1545445711,"msg":"err"}`,
+			},
+			expectedLines: []string{
+				"stdout|out",
+				"stderr|err",
+			},
+			options: []ProcessorOption{header, WithStreamName()},
+		},
+		{
+			name: "unterminated partial",
+			lines: []string{
+				`2018-12-22T02:28:31.000000000Z stdout F {"level":"info","ts":1545445711,"msg":"m1"}`,
+				`2018-12-22T02:28:32.000000000Z stdout P partial`,
+			},
+			expectedLines: []string{
+				"|m1",
+				"partial",
+			},
+			options: []ProcessorOption{header},
+		},
+	})
+}
+
+func TestGoTestJSON(t *testing.T) {
+	event := func(action string, test string, output string) string {
+		line, _ := json.Marshal(map[string]interface{}{"Action": action, "Package": "pkg", "Test": test, "Output": output, "Elapsed": 0.5})
+		return string(line)
+	}
+
+	runLogTests(t, []logTest{
+		{
+			name: "grouped",
+			lines: []string{
+				`{"Action":"start","Package":"pkg"}`,
+				event("run", "TestA", ""),
+				event("output", "TestA", "=== RUN   TestA\n"),
+				event("output", "TestA", `{"level":"info","ts":1545445711,`),
+				event("output", "TestA", `"msg":"m1"}`+"\n"),
+				event("run", "TestB", ""),
+				event("output", "TestB", "=== RUN   TestB\n"),
+				event("output", "TestB", "    b_test.go:12: failed\n"),
+				event("run", "TestA/sub", ""),
+				event("output", "TestA/sub", `{"level":"info","ts":1545445712,"msg":"m2"}`+"\n"),
+				event("output", "TestA/sub", "    --- PASS: TestA/sub (0.50s)\n"),
+				event("pass", "TestA/sub", ""),
+				event("fail", "TestB", ""),
+				event("pass", "TestA", ""),
+				event("output", "", "FAIL\n"),
+				event("output", "", "FAIL\tpkg\t0.500s\n"),
+				event("fail", "", ""),
+				`not json`,
+			},
+			expectedLines: []string{
+				Colorize("=== RUN   TestA", CyanFg|BoldFm).String(),
+				"m1",
+				"    " + Colorize("=== RUN   TestA/sub", CyanFg|BoldFm).String(),
+				"m2",
+				"    " + Green("--- PASS: TestA/sub (0.50s)").String(),
+				Green("--- PASS: TestA (0.50s)").String(),
+				Colorize("=== RUN   TestB", CyanFg|BoldFm).String(),
+				"    b_test.go:12: failed",
+				Colorize("--- FAIL: TestB (0.50s)", RedFg|BoldFm).String(),
+				Colorize("FAIL\tpkg\t0.500s", RedFg|BoldFm).String(),
+				"not json",
+			},
+			options: []ProcessorOption{WithGoTestJSON(), WithHeaderTemplate(MustNewHeaderTemplate("{{.Message}}"))},
+		},
+		{
+			name: "crashed",
+			lines: []string{
+				event("run", "TestA", ""),
+				event("run", "TestB", ""),
+				event("output", "TestB", "b\n"),
+				event("output", "TestA", "panic: boom\n"),
+			},
+			expectedLines: []string{
+				Colorize("=== RUN   TestA", CyanFg|BoldFm).String(),
+				Colorize("panic: boom", RedFg|BoldFm).String(),
+				Colorize("=== RUN   TestB", CyanFg|BoldFm).String(),
+				"b",
+			},
+			options: []ProcessorOption{WithGoTestJSON()},
+		},
+	})
+}
+
+func TestGoTestJSONPartialOutputSource(t *testing.T) {
+	lines := []string{
+		`{"Action":"run","Package":"pkg","Test":"TestA"}`,
+		`{"Action":"output","Package":"pkg","Test":"TestA","Output":"unterminated"}`,
+	}
+
+	writer := &bytes.Buffer{}
+	processor := &Processor{output: writer, multilineJSONFieldThreshold: 3}
+	WithGoTestJSON().apply(processor)
+
+	processor.Stream(Source{Name: "api", Scanner: bufio.NewScanner(strings.NewReader(strings.Join(lines, "\n")))})
+
+	apiTag := BrightBlue("api |").String()
+	require.Equal(t, []string{
+		apiTag + " " + Colorize("=== RUN   TestA", CyanFg|BoldFm).String(),
+		apiTag + " unterminated",
+	}, strings.Split(writer.String(), "\n"))
+}
+
+func TestGoroutineDump(t *testing.T) {
+	dump := []string{
+		`panic: boom`,
+		``,
+		`goroutine 1 [running]:`,
+		`main.(*T).Run(0xc000010000, 0x1)`,
+		"\t/app/main.go:12 +0x1d",
+		`main.main()`,
+		"\t/app/main.go:20 +0x25",
+		``,
+		`goroutine 2 [force gc (idle)]:`,
+		`runtime.gopark(0x0?, 0x0?)`,
+		"\t/usr/local/go/src/runtime/proc.go:435 +0xce",
+		`created by runtime.init.7 in goroutine 1`,
+		"\t/usr/local/go/src/runtime/proc.go:339 +0x1a",
+		``,
+		`{"level":"info","ts":1545445711,"msg":"m1"}`,
+	}
+
+	panicLines := []string{
+		Colorize("panic: boom", RedFg|BoldFm).String(),
+		"",
+		Bold("goroutine 1").String() + " " + Yellow("[running]").String() + ":",
+		Cyan("main.(*T).Run").String() + Gray(12, "(0xc000010000, 0x1)").String(),
+		"\t/app/main.go:12" + Gray(12, " +0x1d").String(),
+		Cyan("main.main").String() + Gray(12, "()").String(),
+		"\t/app/main.go:20" + Gray(12, " +0x25").String(),
+		"",
+	}
+
+	runLogTests(t, []logTest{
+		{
+			name:  "rendered",
+			lines: dump,
+			expectedLines: append(append([]string{}, panicLines...),
+				Bold("goroutine 2").String()+" "+Yellow("[force gc (idle)]").String()+":",
+				Cyan("runtime.gopark").String()+Gray(12, "(0x0?, 0x0?)").String(),
+				"\t/usr/local/go/src/runtime/proc.go:435"+Gray(12, " +0xce").String(),
+				Gray(12, "created by ").String()+Cyan("runtime.init.7").String()+Gray(12, " in goroutine 1").String(),
+				"\t/usr/local/go/src/runtime/proc.go:339"+Gray(12, " +0x1a").String(),
+				"m1",
+			),
+			options: []ProcessorOption{WithHeaderTemplate(MustNewHeaderTemplate("{{.Message}}"))},
+		},
+		{
+			name:  "runtime goroutines collapsed",
+			lines: dump,
+			expectedLines: append(append([]string{}, panicLines...),
+				Gray(12, "… 1 runtime goroutine").String(),
+				"m1",
+			),
+			options: []ProcessorOption{WithRuntimeGoroutinesCollapsed(), WithHeaderTemplate(MustNewHeaderTemplate("{{.Message}}"))},
+		},
+	})
+}
+
+func TestMerge(t *testing.T) {
+	api := []string{
+		`starting api`,
+		`{"level":"info","ts":"2018-12-21T21:28:31.000Z","msg":"a1"}`,
+		`{"level":"error","ts":"2018-12-21T21:28:33.000Z","msg":"a2"}`,
+		`panic: boom`,
+		`goroutine 1 [running]:`,
+		`{"level":"info","ts":"2018-12-21T21:28:35.000Z","msg":"a3"}`,
+	}
+	worker := []string{
+		`{"level":"info","ts":"2018-12-21T21:28:32.000Z","msg":"w1"}`,
+		`{"level":"info","msg":"w2"}`,
+		`{"level":"info","ts":"2018-12-21T21:28:33.000Z","msg":"w3"}`,
+		`{"other":"format"}`,
+		`{"level":"info","ts":"2018-12-21T21:28:36.000Z","msg":"w4"}`,
+	}
+
+	source := func(name string, lines []string) Source {
+		return Source{Name: name, Scanner: bufio.NewScanner(strings.NewReader(strings.Join(lines, "\n")))}
+	}
+
+	writer := &bytes.Buffer{}
+	processor := &Processor{output: writer, multilineJSONFieldThreshold: 3}
+	WithHeaderTemplate(MustNewHeaderTemplate("{{.Source}} {{.Message}}")).apply(processor)
+
+	processor.Merge(source("api", api), source("worker", worker))
+
+	apiTag, workerTag := BrightBlue("api    |").String(), BrightYellow("worker |").String()
+	require.Equal(t, []string{
+		apiTag + " starting api",
+		apiTag + " a1",
+		workerTag + " w1",
+		workerTag + ` {"level":"info","msg":"w2"}`,
+		apiTag + " a2",
+		apiTag + " " + Colorize("panic: boom", RedFg|BoldFm).String(),
+		apiTag + " " + Bold("goroutine 1").String() + " " + Yellow("[running]").String() + ":",
+		workerTag + " w3",
+		workerTag + ` {"other":"format"}`,
+		apiTag + " a3",
+		workerTag + " w4",
+	}, strings.Split(writer.String(), "\n"))
+}
+
+func TestMergeNonStringStandardFields(t *testing.T) {
+	lines := []string{
+		`{"level":"info","ts":"2018-12-21T21:28:31.000Z","msg":"a1"}`,
+		`{"level":30,"ts":1,"msg":"x"}`,
+		`{"level":"info","ts":"2018-12-21T21:28:32.000Z","msg":"a2","caller":1}`,
+	}
+
+	writer := &bytes.Buffer{}
+	processor := &Processor{output: writer, multilineJSONFieldThreshold: 3}
+	WithHeaderTemplate(MustNewHeaderTemplate("{{.Message}}")).apply(processor)
+
+	processor.Merge(Source{Name: "api", Scanner: bufio.NewScanner(strings.NewReader(strings.Join(lines, "\n")))})
+
+	apiTag := BrightBlue("api |").String()
+	require.Equal(t, []string{
+		"a1",
+		apiTag + ` {"level":30,"ts":1,"msg":"x"}`,
+		apiTag + ` {"level":"info","ts":"2018-12-21T21:28:32.000Z","msg":"a2","caller":1}`,
+	}, strings.Split(writer.String(), "\n"))
+}
+
+func TestParseTimeMode(t *testing.T) {
+	mode, err := ParseTimeMode("Since-Start")
+	require.NoError(t, err)
+	require.Equal(t, TimeModeSinceStart, mode)
+
+	_, err = ParseTimeMode("unknown")
+	require.Error(t, err)
+}
+
 func runLogTests(t *testing.T, tests []logTest) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
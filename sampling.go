@@ -0,0 +1,187 @@
+package zapp
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/logrusorgru/aurora"
+)
+
+// samplerCounterCount is the amount of counters records are hashed into, like zap's own
+// sampler, distinct keys can collide and share the same counter.
+const samplerCounterCount = 4096
+
+type samplerCounter struct {
+	resetAt time.Time
+	count   int
+}
+
+// sampler is the state of `WithMaxRate` and `WithDebugSampling`, it counts the records per
+// (level, logger, message) key over each tick and the dropped ones per logger until the
+// next `[dropped N lines from logger X]` notice.
+type sampler struct {
+	counters       [samplerCounterCount]samplerCounter
+	dropped        map[string]int
+	noticeAt       time.Time
+	noticeDeadline time.Time
+}
+
+// ParseMaxRate parses a rate of the form `N/s`, the unit can also be `m`, `h` or any Go
+// duration like `500ms`, it returns the count and the tick it applies to.
+func ParseMaxRate(value string) (int, time.Duration, error) {
+	count, unit, found := strings.Cut(value, "/")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid rate %q, expected format is 'N/s'", value)
+	}
+
+	n, err := strconv.Atoi(count)
+	if err != nil || n <= 0 {
+		return 0, 0, fmt.Errorf("invalid rate %q, count must be a positive integer", value)
+	}
+
+	if unit == "s" || unit == "m" || unit == "h" {
+		unit = "1" + unit
+	}
+
+	tick, err := time.ParseDuration(unit)
+	if err != nil || tick <= 0 {
+		return 0, 0, fmt.Errorf("invalid rate %q, unit must be 's', 'm', 'h' or a positive duration", value)
+	}
+
+	return n, tick, nil
+}
+
+// ParseSampling parses a sampling ratio of the form `1/N` and returns N.
+func ParseSampling(value string) (int, error) {
+	numerator, denominator, found := strings.Cut(value, "/")
+	if !found || numerator != "1" {
+		return 0, fmt.Errorf("invalid sampling %q, expected format is '1/N'", value)
+	}
+
+	n, err := strconv.Atoi(denominator)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid sampling %q, N must be a positive integer", value)
+	}
+
+	return n, nil
+}
+
+func (p *Processor) samplingEnabled() bool {
+	return p.maxRate > 0 || p.debugSampling > 0
+}
+
+// sampleRecord reports whether the record is kept according to the sampling configuration,
+// it mirrors zap's sampler: over each tick, the first records of a key are kept and then
+// only one every `thereafter`. Ticks are based on the record timestamp when available.
+func (p *Processor) sampleRecord(rec *record) bool {
+	if !p.samplingEnabled() {
+		return true
+	}
+
+	if p.sampler == nil {
+		p.sampler = &sampler{dropped: map[string]int{}}
+	}
+
+	now := time.Now()
+	if rec.timestamp != nil {
+		now = *rec.timestamp
+	}
+
+	if len(p.sampler.dropped) > 0 && !now.Before(p.sampler.noticeAt) {
+		p.writeDropNotices()
+	}
+
+	first, thereafter := p.maxRate, 0
+	if p.debugSampling > 0 && strings.EqualFold(rec.severity, "debug") {
+		thereafter = p.debugSampling
+		if first == 0 {
+			first = 1
+		}
+	}
+
+	if first == 0 {
+		return true
+	}
+
+	tick := p.samplingTick()
+	counter := &p.sampler.counters[samplerKey(rec)%samplerCounterCount]
+	if !now.Before(counter.resetAt) {
+		counter.count = 0
+		counter.resetAt = now.Add(tick)
+	}
+
+	counter.count++
+	if counter.count <= first || (thereafter > 0 && (counter.count-first)%thereafter == 0) {
+		return true
+	}
+
+	if len(p.sampler.dropped) == 0 {
+		p.sampler.noticeAt = now.Add(tick)
+		p.sampler.noticeDeadline = time.Now().Add(tick)
+	}
+	p.sampler.dropped[recordLoggerKey(rec)]++
+
+	return false
+}
+
+func (p *Processor) samplingTick() time.Duration {
+	if p.maxRateTick <= 0 {
+		return time.Second
+	}
+
+	return p.maxRateTick
+}
+
+// dropNoticeDeadline returns the time at which the pending drop notices must be written,
+// false if there is none.
+func (p *Processor) dropNoticeDeadline() (time.Time, bool) {
+	if p.sampler == nil || len(p.sampler.dropped) == 0 {
+		return time.Time{}, false
+	}
+
+	return p.sampler.noticeDeadline, true
+}
+
+// writeDropNotices writes a `[dropped N lines from logger X]` line per logger for which
+// records were dropped since the last notices.
+func (p *Processor) writeDropNotices() {
+	if p.sampler == nil || len(p.sampler.dropped) == 0 {
+		return
+	}
+
+	p.flushDedupe()
+
+	loggers := make([]string, 0, len(p.sampler.dropped))
+	for logger := range p.sampler.dropped {
+		loggers = append(loggers, logger)
+	}
+	sort.Strings(loggers)
+
+	for _, logger := range loggers {
+		notice := fmt.Sprintf("[dropped %d lines from logger %s]", p.sampler.dropped[logger], logger)
+		if logger == "" {
+			notice = fmt.Sprintf("[dropped %d lines]", p.sampler.dropped[logger])
+		}
+
+		p.writeLine(Gray(12, notice).String())
+	}
+
+	p.sampler.dropped = map[string]int{}
+}
+
+func samplerKey(rec *record) uint32 {
+	hash := fnv.New32a()
+	hash.Write([]byte(strings.ToLower(rec.severity)))
+	hash.Write([]byte{0})
+	hash.Write([]byte(recordLoggerKey(rec)))
+	hash.Write([]byte{0})
+	hash.Write([]byte(rec.message))
+	hash.Write([]byte{0})
+	hash.Write([]byte(rec.source))
+
+	return hash.Sum32()
+}
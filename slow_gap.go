@@ -0,0 +1,29 @@
+package zapp
+
+import (
+	"bytes"
+	"time"
+
+	. "github.com/logrusorgru/aurora"
+)
+
+var slowGapColors = []Color{YellowFg, RedFg}
+
+// slowGapColor returns the color of the highest slow threshold tier exceeded by the gap
+// between the record and the previous one, 0 if none is exceeded.
+func (p *Processor) slowGapColor(gap time.Duration) Color {
+	color := Color(0)
+	for i, threshold := range p.slowThresholds {
+		if threshold > 0 && gap >= threshold && i < len(slowGapColors) {
+			color = slowGapColors[i]
+		}
+	}
+
+	return color
+}
+
+// writeSlowGapSeparator writes a line like `──── 4.2s gap ────` followed by a newline.
+func writeSlowGapSeparator(buffer *bytes.Buffer, gap time.Duration, color Color) {
+	buffer.WriteString(Colorize("──── "+durationToString(gap)+" gap ────", color).String())
+	buffer.WriteByte('\n')
+}
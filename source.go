@@ -0,0 +1,113 @@
+package zapp
+
+import (
+	"bufio"
+	"fmt"
+	"hash/crc32"
+	"sync"
+
+	. "github.com/logrusorgru/aurora"
+)
+
+// sourcesPalette are the colors of source tags like docker-compose uses, red is left out
+// so that tags are not mistaken for errors.
+var sourcesPalette = []Color{
+	CyanFg, YellowFg, GreenFg, MagentaFg, BlueFg,
+	BrightFg | CyanFg, BrightFg | YellowFg, BrightFg | GreenFg, BrightFg | MagentaFg, BrightFg | BlueFg,
+}
+
+// Source is a named input processed by `Processor.Stream` or `Processor.Merge`, its name is
+// rendered as a tag in front of each of its lines and is available to filters as `source`.
+type Source struct {
+	Name    string
+	Scanner *bufio.Scanner
+}
+
+// sourceLine is a line read from the named source, the name is empty for the processor's
+// own scanner unless `WithSource` is used.
+type sourceLine struct {
+	source string
+	text   string
+}
+
+// WithSource tags the lines read by the processor with the source name, like when multiple
+// inputs are prettified by separate processes sharing the same terminal.
+func WithSource(name string) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.source = name
+	})
+}
+
+// registerSource returns the color of the source, picked from the name's hash so that a
+// source has the same color across runs, and widens the tags so that those of all known
+// sources are aligned.
+func (p *Processor) registerSource(name string) Color {
+	if color, found := p.sourceColors[name]; found {
+		return color
+	}
+
+	if p.sourceColors == nil {
+		p.sourceColors = map[string]Color{}
+	}
+
+	color := sourcesPalette[crc32.ChecksumIEEE([]byte(name))%uint32(len(sourcesPalette))]
+	p.sourceColors[name] = color
+
+	if len(name) > p.sourceWidth {
+		p.sourceWidth = len(name)
+	}
+
+	return color
+}
+
+// sourceTag renders the tag of the source like `api |`, padded to the widest known source.
+func (p *Processor) sourceTag(name string) string {
+	color := p.registerSource(name)
+
+	return Colorize(fmt.Sprintf("%-*s |", p.sourceWidth, name), color).String()
+}
+
+// writeSourceLine writes a line that is not a log line, prefixed by the tag of its source
+// if it has one.
+func (p *Processor) writeSourceLine(source string, line string) {
+	if source == "" {
+		p.writeLine(line)
+		return
+	}
+
+	p.writeLine(p.sourceTag(source) + " " + line)
+}
+
+// Stream processes the sources together instead of the processor's scanner, their lines are
+// printed as soon as they are read, each one tagged with the name of its source.
+func (p *Processor) Stream(sources ...Source) {
+	for _, source := range sources {
+		p.registerSource(source.Name)
+	}
+
+	lines := make(chan sourceLine)
+
+	var wg sync.WaitGroup
+	for _, source := range sources {
+		wg.Add(1)
+		go func(source Source) {
+			defer wg.Done()
+
+			for source.Scanner.Scan() {
+				lines <- sourceLine{source: source.Name, text: source.Scanner.Text()}
+			}
+
+			if err := source.Scanner.Err(); err != nil {
+				p.debugPrintln("Scanner of source %q terminated with error: %s", source.Name, err)
+			}
+		}(source)
+	}
+
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+
+	p.processWithEvents(lines)
+	p.finish()
+}
@@ -0,0 +1,144 @@
+package zapp
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	. "github.com/logrusorgru/aurora"
+)
+
+// stackFrameLocationRegex matches the location line of a frame, like `\t/app/main.go:12`,
+// optionally followed by the program counter offset like ` +0x1d`.
+var stackFrameLocationRegex = regexp.MustCompile(`^\t(.+):(\d+)( .*)?$`)
+
+type stackFrameKind int
+
+const (
+	stackFrameApplication stackFrameKind = iota
+	stackFrameStandardLibrary
+	stackFrameThirdParty
+)
+
+// stackFrame is a frame of a stack trace like `zap.Stack` renders them, the function on one
+// line followed by its location on the next one.
+type stackFrame struct {
+	function string
+	file     string
+	line     string
+	offset   string
+	pkg      string
+	kind     stackFrameKind
+}
+
+// WithStackDepth renders at most `depth` frames of stack traces, a line folding library
+// frames counting as one, the remaining ones are summarized in a single `… N more frames`
+// line. A value of 0 renders all the frames.
+func WithStackDepth(depth int) ProcessorOption {
+	return ProcessorOptionFunc(func(p *Processor) {
+		p.stackDepth = depth
+	})
+}
+
+// parseStackFrames parses the frames of the stack trace, false is returned if it's not in
+// the expected function and location pairs format.
+func parseStackFrames(stacktrace string) ([]stackFrame, bool) {
+	lines := strings.Split(strings.TrimRight(stacktrace, "\n"), "\n")
+	if len(lines)%2 != 0 {
+		return nil, false
+	}
+
+	frames := make([]stackFrame, 0, len(lines)/2)
+	for i := 0; i < len(lines); i += 2 {
+		function := lines[i]
+		match := stackFrameLocationRegex.FindStringSubmatch(lines[i+1])
+		if function == "" || strings.HasPrefix(function, "\t") || match == nil {
+			return nil, false
+		}
+
+		frame := stackFrame{function: function, file: match[1], line: match[2], offset: match[3], pkg: stackFunctionPackage(function)}
+		frame.kind = stackFrameKindOf(frame)
+		frames = append(frames, frame)
+	}
+
+	return frames, true
+}
+
+// stackFunctionPackage returns the import path of the package of a function like
+// `net/http.(*conn).serve` or `github.com/acme/api.(*Server).Run.func1`.
+func stackFunctionPackage(function string) string {
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+
+	return function
+}
+
+// stackFrameKindOf tells frames of the application from those of the standard library, read
+// from the `src` directory of GOROOT like `/usr/local/go/src/net/http/server.go`, and those
+// of third-party modules, read from the module cache or a vendor directory. The import path
+// is not enough for the standard library, a module may be named without a dot like `myapp`.
+func stackFrameKindOf(frame stackFrame) stackFrameKind {
+	file := strings.ReplaceAll(frame.file, "\\", "/")
+	if strings.Contains(file, "/pkg/mod/") || strings.Contains(file, "/vendor/") {
+		return stackFrameThirdParty
+	}
+
+	root := strings.SplitN(frame.pkg, "/", 2)[0]
+	if frame.pkg != "main" && !strings.Contains(root, ".") && strings.Contains(file, "/src/"+frame.pkg+"/") {
+		return stackFrameStandardLibrary
+	}
+
+	return stackFrameApplication
+}
+
+// writeStacktrace writes the frames of the stack trace, functions and locations colored
+// differently. Frames of the standard library and of third-party modules are dimmed and
+// consecutive ones of the same package are folded into a single `… N frames in net/http` line.
+// With `WithStackDepth`, a folded line counts as a single frame. A stack trace not in the
+// expected format is written as is.
+func (p *Processor) writeStacktrace(buffer *bytes.Buffer, stacktrace string) {
+	frames, ok := parseStackFrames(stacktrace)
+	if !ok {
+		buffer.WriteString("    " + strings.ReplaceAll(stacktrace, "\n", "\n    "))
+		return
+	}
+
+	var lines []string
+	rendered := 0
+	for i := 0; i < len(frames); {
+		if p.stackDepth > 0 && rendered == p.stackDepth {
+			text := fmt.Sprintf("… %d more frames", len(frames)-i)
+			if len(frames)-i == 1 {
+				text = "… 1 more frame"
+			}
+
+			lines = append(lines, grayText(text))
+			break
+		}
+
+		frame := frames[i]
+
+		end := i + 1
+		if frame.kind != stackFrameApplication {
+			for end < len(frames) && frames[end].kind != stackFrameApplication && frames[end].pkg == frame.pkg {
+				end++
+			}
+		}
+
+		if end-i > 1 {
+			lines = append(lines, grayText(fmt.Sprintf("… %d frames in %s", end-i, frame.pkg)))
+		} else if frame.kind != stackFrameApplication {
+			lines = append(lines, grayText(frame.function), "\t"+grayText(frame.file+":"+frame.line+frame.offset))
+		} else {
+			lines = append(lines, Cyan(frame.function).String(), "\t"+frame.file+":"+Yellow(frame.line).String()+grayText(frame.offset))
+		}
+
+		rendered++
+		i = end
+	}
+
+	buffer.WriteString("    " + strings.Join(lines, "\n    "))
+}
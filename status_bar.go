@@ -0,0 +1,230 @@
+package zapp
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// StatusBar is a status line sticking at the bottom of a terminal showing live statistics
+// about the processed lines: lines per second, errors and warnings counts, the time of the
+// last error and the filters in effect. The bottom row is reserved through a scroll region
+// so that regular output scrolls above it.
+//
+// The status bar is redrawn from its own goroutine, all other writes to the terminal must
+// go through writers obtained from `Wrap` so that they are never interleaved with a redraw.
+type StatusBar struct {
+	mutex    sync.Mutex
+	terminal *os.File
+	filters  string
+	width    int
+	height   int
+	stop     chan struct{}
+	done     chan struct{}
+
+	lines       int
+	linesAtTick int
+	lastTick    time.Time
+	rate        float64
+	errors      int
+	warnings    int
+	lastError   *time.Time
+	lastErrorAt time.Time
+
+	// localizeTime converts the time of the last error to the time zone of the rendered lines
+	localizeTime func(time.Time) time.Time
+}
+
+// NewStatusBar returns a status bar drawn on `terminal` and listing `filters` as the
+// filters in effect, use `IsTerminal` to check that the output supports it first.
+func NewStatusBar(terminal *os.File, filters ...string) *StatusBar {
+	return &StatusBar{terminal: terminal, filters: strings.Join(filters, ", ")}
+}
+
+// IsTerminal reports whether file is a terminal on which a status bar can be drawn.
+func IsTerminal(file *os.File) bool {
+	return term.IsTerminal(int(file.Fd()))
+}
+
+// Start reserves the bottom row of the terminal and redraws the status bar each interval
+// until `Stop` is called.
+func (b *StatusBar) Start(interval time.Duration) {
+	b.mutex.Lock()
+	b.lastTick = time.Now()
+	b.resize()
+	b.draw(time.Now())
+	b.mutex.Unlock()
+
+	b.stop = make(chan struct{})
+	b.done = make(chan struct{})
+
+	go func() {
+		defer close(b.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-b.stop:
+				return
+			case now := <-ticker.C:
+				b.mutex.Lock()
+				b.tick(now)
+				b.resize()
+				b.draw(now)
+				b.mutex.Unlock()
+			}
+		}
+	}()
+}
+
+// Stop stops redrawing, clears the status bar and gives the bottom row back to the output.
+func (b *StatusBar) Stop() {
+	if b.stop == nil {
+		return
+	}
+
+	close(b.stop)
+	<-b.done
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.height > 1 {
+		fmt.Fprintf(b.terminal, "\x1b7\x1b[%d;1H\x1b[2K\x1b[r\x1b8", b.height)
+	}
+}
+
+// Wrap returns a writer writing to `writer` without ever being interleaved with a redraw
+// of the status bar.
+func (b *StatusBar) Wrap(writer io.Writer) io.Writer {
+	return &statusBarWriter{bar: b, writer: writer}
+}
+
+type statusBarWriter struct {
+	bar    *StatusBar
+	writer io.Writer
+}
+
+func (w *statusBarWriter) Write(data []byte) (int, error) {
+	w.bar.mutex.Lock()
+	defer w.bar.mutex.Unlock()
+
+	return w.writer.Write(data)
+}
+
+func (b *StatusBar) countNonRecord() {
+	if b == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	b.lines++
+	b.mutex.Unlock()
+}
+
+// setLocalizeTime sets the conversion of the last error time, the status bar may already be
+// drawn from its own goroutine.
+func (b *StatusBar) setLocalizeTime(localize func(time.Time) time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.localizeTime = localize
+}
+
+func (b *StatusBar) countRecord(rec *record) {
+	if b == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.lines++
+
+	switch rank := levelsRank[strings.ToLower(rec.severity)]; {
+	case rank >= levelsRank["error"]:
+		b.errors++
+		b.lastErrorAt = time.Now()
+		b.lastError = rec.timestamp
+		if b.lastError == nil {
+			b.lastError = &b.lastErrorAt
+		}
+
+	case rank == levelsRank["warn"]:
+		b.warnings++
+	}
+}
+
+func (b *StatusBar) tick(now time.Time) {
+	if elapsed := now.Sub(b.lastTick); elapsed > 0 {
+		b.rate = float64(b.lines-b.linesAtTick) / elapsed.Seconds()
+	}
+
+	b.linesAtTick = b.lines
+	b.lastTick = now
+}
+
+// resize reserves the bottom row of the terminal through a scroll region, it's performed
+// again when the size of the terminal changes.
+func (b *StatusBar) resize() {
+	width, height, err := term.GetSize(int(b.terminal.Fd()))
+	if err != nil || (width == b.width && height == b.height) {
+		return
+	}
+
+	if b.height == 0 {
+		// Make room for the status bar when the cursor is on the bottom row
+		io.WriteString(b.terminal, "\n\x1b[1A")
+	}
+
+	b.width, b.height = width, height
+	if height > 1 {
+		fmt.Fprintf(b.terminal, "\x1b7\x1b[1;%dr\x1b8", height-1)
+	}
+}
+
+func (b *StatusBar) draw(now time.Time) {
+	if b.height <= 1 {
+		return
+	}
+
+	fmt.Fprintf(b.terminal, "\x1b7\x1b[%d;1H\x1b[2K\x1b[7m%s\x1b[0m\x1b8", b.height, b.status(now, b.width))
+}
+
+// status returns the text of the status bar, padded or truncated to `width` characters.
+func (b *StatusBar) status(now time.Time, width int) string {
+	parts := []string{
+		fmt.Sprintf("%.1f lines/s", b.rate),
+		fmt.Sprintf("%d errors", b.errors),
+		fmt.Sprintf("%d warnings", b.warnings),
+	}
+
+	if b.lastError != nil {
+		lastError := b.lastError.Local()
+		if b.localizeTime != nil {
+			lastError = b.localizeTime(*b.lastError)
+		}
+
+		parts = append(parts, fmt.Sprintf("last error %s (%s ago)",
+			lastError.Format("15:04:05"), durationToString(now.Sub(b.lastErrorAt).Truncate(time.Second))))
+	}
+
+	if b.filters != "" {
+		parts = append(parts, b.filters)
+	}
+
+	text := " " + strings.Join(parts, " │ ") + " "
+	if count := utf8.RuneCountInString(text); count < width {
+		return text + strings.Repeat(" ", width-count)
+	}
+
+	return string([]rune(text)[:width])
+}
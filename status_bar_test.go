@@ -0,0 +1,53 @@
+package zapp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusBarStatus(t *testing.T) {
+	bar := NewStatusBar(nil, `filter: level != "debug"`, "dedupe")
+
+	executeProcessorTest([]string{
+		`{"level":"info","ts":"2018-12-21T21:28:31.000Z","msg":"a"}`,
+		`{"level":"warn","ts":"2018-12-21T21:28:32.000Z","msg":"b"}`,
+		`not json`,
+		`{"level":"error","ts":"2018-12-21T21:28:33.000Z","msg":"c"}`,
+	}, WithStatusBar(bar))
+
+	start := time.Now()
+	bar.lastTick = start
+	bar.tick(start.Add(2 * time.Second))
+	bar.lastErrorAt = start
+
+	status := bar.status(start.Add(65*time.Second+500*time.Millisecond), 120)
+	require.Equal(t, 120, utf8.RuneCountInString(status))
+	require.Equal(t, ` 2.0 lines/s │ 1 errors │ 1 warnings │ last error 16:28:33 (1m 5s ago) │ filter: level != "debug", dedupe`, strings.TrimRight(status, " "))
+
+	require.Equal(t, " 2.0 lines/s │ 1 errors", bar.status(start, 23))
+}
+
+func TestStatusBarStatusTimeZone(t *testing.T) {
+	bar := NewStatusBar(nil)
+
+	executeProcessorTest([]string{
+		`{"level":"error","ts":"2018-12-21T21:28:33.000Z","msg":"c"}`,
+	}, WithStatusBar(bar), WithTimeZone(time.UTC))
+
+	bar.lastErrorAt = time.Now()
+	require.Contains(t, bar.status(bar.lastErrorAt, 120), "last error 21:28:33 (0s ago)")
+}
+
+func TestStatusBarWrap(t *testing.T) {
+	bar := NewStatusBar(nil)
+	buffer := &bytes.Buffer{}
+
+	_, err := bar.Wrap(buffer).Write([]byte("line"))
+	require.NoError(t, err)
+	require.Equal(t, "line", buffer.String())
+}
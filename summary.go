@@ -0,0 +1,198 @@
+package zapp
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// summaryMaxMessages bounds the amount of distinct messages counted for the top messages,
+// the messages seen once the limit is reached are only counted as other messages.
+const summaryMaxMessages = 10000
+
+// summaryStats aggregates the statistics of the input printed by `WithSummary`, lines are
+// counted whether they are printed or not.
+type summaryStats struct {
+	startedAt     time.Time
+	lines         int
+	records       int
+	nonJSON       int
+	unparseable   int
+	levels        map[string]int
+	loggers       map[string]int
+	sources       map[string]int
+	messages      map[string]int
+	otherMessages int
+	first         *time.Time
+	last          *time.Time
+}
+
+func newSummaryStats() *summaryStats {
+	return &summaryStats{
+		startedAt: time.Now(),
+		levels:    map[string]int{},
+		loggers:   map[string]int{},
+		sources:   map[string]int{},
+		messages:  map[string]int{},
+	}
+}
+
+func (s *summaryStats) countNonJSON() {
+	if s == nil {
+		return
+	}
+
+	s.lines++
+	s.nonJSON++
+}
+
+func (s *summaryStats) countUnparseable() {
+	if s == nil {
+		return
+	}
+
+	s.lines++
+	s.unparseable++
+}
+
+func (s *summaryStats) countRecord(rec *record) {
+	if s == nil {
+		return
+	}
+
+	s.lines++
+	s.records++
+	s.levels[strings.ToLower(rec.severity)]++
+	s.loggers[recordLoggerKey(rec)]++
+	if rec.source != "" {
+		s.sources[rec.source]++
+	}
+
+	if _, found := s.messages[rec.message]; found || len(s.messages) < summaryMaxMessages {
+		s.messages[rec.message]++
+	} else {
+		s.otherMessages++
+	}
+
+	if timestamp := rec.timestamp; timestamp != nil {
+		if s.first == nil || timestamp.Before(*s.first) {
+			s.first = timestamp
+		}
+
+		if s.last == nil || timestamp.After(*s.last) {
+			s.last = timestamp
+		}
+	}
+}
+
+// writeSummary writes the statistics gathered so far to the summary output, it's a no-op
+// when the summary is not enabled.
+func (p *Processor) writeSummary() {
+	if p.stats == nil || p.summaryOutput == nil {
+		return
+	}
+
+	p.terminateOutput()
+
+	s := p.stats
+	out := p.summaryOutput
+
+	fmt.Fprintln(out, "Summary")
+	fmt.Fprintf(out, "  %-13s%d (%d log lines, %d non-JSON, %d unparseable)\n", "Lines", s.lines, s.records, s.nonJSON, s.unparseable)
+
+	elapsed := time.Since(s.startedAt)
+	if s.first != nil {
+		fmt.Fprintf(out, "  %-13s%s\n", "First", p.formatTime(p.localizeTime(*s.first)))
+		fmt.Fprintf(out, "  %-13s%s (%s)\n", "Last", p.formatTime(p.localizeTime(*s.last)), durationToString(s.last.Sub(*s.first)))
+
+		if span := s.last.Sub(*s.first); span > 0 {
+			elapsed = span
+		}
+	}
+
+	if elapsed > 0 {
+		fmt.Fprintf(out, "  %-13s%.1f lines/s\n", "Rate", float64(s.lines)/elapsed.Seconds())
+	}
+
+	if len(s.levels) > 0 {
+		levels := sortedCounts(s.levels, func(left, right string) bool {
+			return levelsRank[left] > levelsRank[right]
+		})
+
+		entries := make([]string, len(levels))
+		for i, level := range levels {
+			entries[i] = fmt.Sprintf("%s %d", strings.ToUpper(level), s.levels[level])
+		}
+
+		fmt.Fprintf(out, "  %-13s%s\n", "Levels", strings.Join(entries, ", "))
+	}
+
+	if len(s.loggers) > 0 {
+		loggers := sortedCounts(s.loggers, nil)
+
+		entries := make([]string, len(loggers))
+		for i, logger := range loggers {
+			name := logger
+			if name == "" {
+				name = "<none>"
+			}
+
+			entries[i] = fmt.Sprintf("%s %d", name, s.loggers[logger])
+		}
+
+		fmt.Fprintf(out, "  %-13s%s\n", "Loggers", strings.Join(entries, ", "))
+	}
+
+	if len(s.sources) > 0 {
+		sources := sortedCounts(s.sources, nil)
+
+		entries := make([]string, len(sources))
+		for i, source := range sources {
+			entries[i] = fmt.Sprintf("%s %d", source, s.sources[source])
+		}
+
+		fmt.Fprintf(out, "  %-13s%s\n", "Sources", strings.Join(entries, ", "))
+	}
+
+	if p.summaryTopMessages > 0 && len(s.messages) > 0 {
+		fmt.Fprintf(out, "  %s\n", "Top messages")
+		writeTopMessages(out, s.messages, p.summaryTopMessages)
+	}
+}
+
+func writeTopMessages(out io.Writer, messages map[string]int, count int) {
+	top := sortedCounts(messages, nil)
+	if len(top) > count {
+		top = top[:count]
+	}
+
+	width := len(fmt.Sprintf("%d", messages[top[0]]))
+	for _, message := range top {
+		fmt.Fprintf(out, "    %*d  %s\n", width, messages[message], message)
+	}
+}
+
+// sortedCounts returns the keys of counts ordered by `less` when provided, by decreasing
+// count otherwise, ties are ordered by key.
+func sortedCounts(counts map[string]int, less func(left, right string) bool) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if less != nil && less(keys[i], keys[j]) != less(keys[j], keys[i]) {
+			return less(keys[i], keys[j])
+		}
+
+		if less == nil && counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+
+		return keys[i] < keys[j]
+	})
+
+	return keys
+}
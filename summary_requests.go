@@ -0,0 +1,37 @@
+package zapp
+
+import (
+	"bufio"
+	"os"
+)
+
+// NotifySummaryRequests sends a value on `requests` each time the user asks for the summary
+// while the input is still being processed, that is on `SIGUSR1` (where supported) and on
+// each Enter key press in the terminal, the latter only when standard input is not the
+// terminal itself. Requests received while one is pending are coalesced.
+func NotifySummaryRequests(requests chan<- struct{}) {
+	request := func() {
+		select {
+		case requests <- struct{}{}:
+		default:
+		}
+	}
+
+	notifySummarySignal(request)
+
+	if stat, err := os.Stdin.Stat(); err != nil || stat.Mode()&os.ModeCharDevice != 0 {
+		return
+	}
+
+	terminal, err := os.Open(terminalDevice)
+	if err != nil {
+		return
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(terminal)
+		for scanner.Scan() {
+			request()
+		}
+	}()
+}
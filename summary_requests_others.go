@@ -0,0 +1,23 @@
+//go:build darwin || linux
+// +build darwin linux
+
+package zapp
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+const terminalDevice = "/dev/tty"
+
+func notifySummarySignal(request func()) {
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGUSR1)
+
+	go func() {
+		for range signalChan {
+			request()
+		}
+	}()
+}
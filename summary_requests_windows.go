@@ -0,0 +1,7 @@
+package zapp
+
+const terminalDevice = "CONIN$"
+
+// notifySummarySignal is a no-op, there is no `SIGUSR1` on Windows.
+func notifySummarySignal(request func()) {
+}
@@ -0,0 +1,86 @@
+package zapp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTimeFormat is the Go time layout used to render timestamps when none is provided.
+const DefaultTimeFormat = "2006-01-02 15:04:05.000 MST"
+
+const (
+	unixSecondsTimeFormat = "unix"
+	unixMillisTimeFormat  = "unix-ms"
+)
+
+var timeFormatPresets = map[string]string{
+	"default":      DefaultTimeFormat,
+	"rfc3339":      time.RFC3339,
+	"rfc3339-ms":   "2006-01-02T15:04:05.000Z07:00",
+	"rfc3339-nano": time.RFC3339Nano,
+	"datetime":     time.DateTime,
+	"time-only":    "15:04:05.000",
+	"kitchen":      time.Kitchen,
+	"unix":         unixSecondsTimeFormat,
+	"unix-ms":      unixMillisTimeFormat,
+}
+
+// TimeFormatPresets returns the sorted list of preset names accepted by `WithTimeFormat`.
+func TimeFormatPresets() []string {
+	names := make([]string, 0, len(timeFormatPresets))
+	for name := range timeFormatPresets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// LoadTimeZone returns the location for `name` which is either `local`, `utc` (both case
+// insensitive) or an IANA time zone name like `America/Toronto`.
+func LoadTimeZone(name string) (*time.Location, error) {
+	switch strings.ToLower(name) {
+	case "", "local":
+		return time.Local, nil
+	case "utc":
+		return time.UTC, nil
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", name, err)
+	}
+
+	return location, nil
+}
+
+// localizeTime moves the timestamp into the configured time zone, when the original time
+// zone is requested, the timestamp is kept as parsed which is the zone the log was written in
+// for string timestamps (numeric timestamps carry no zone and are displayed in the configured one).
+func (p *Processor) localizeTime(timestamp time.Time) time.Time {
+	if p.originalTimeZone && timestamp.Location() != time.Local {
+		return timestamp
+	}
+
+	if p.timeZone == nil {
+		return timestamp.In(time.Local)
+	}
+
+	return timestamp.In(p.timeZone)
+}
+
+func (p *Processor) formatTime(timestamp time.Time) string {
+	switch p.timeFormat {
+	case "":
+		return timestamp.Format(DefaultTimeFormat)
+	case unixSecondsTimeFormat:
+		return strconv.FormatInt(timestamp.Unix(), 10)
+	case unixMillisTimeFormat:
+		return strconv.FormatInt(timestamp.UnixMilli(), 10)
+	}
+
+	return timestamp.Format(p.timeFormat)
+}
@@ -0,0 +1,141 @@
+package zapp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeMode controls the relative time shown next to the timestamp of each line.
+type TimeMode int
+
+const (
+	// TimeModeNone shows no relative time
+	TimeModeNone TimeMode = iota
+
+	// TimeModeDelta shows the delta since the previous line
+	TimeModeDelta
+
+	// TimeModeElapsed shows the time elapsed since the first line, like `+12.345s`
+	TimeModeElapsed
+
+	// TimeModeSinceStart shows the time elapsed since the last line matching the start
+	// marker (see `WithStartMarker`), or since the first line if none matched yet
+	TimeModeSinceStart
+
+	// TimeModeLoggerDelta shows the delta since the previous line of the same logger
+	TimeModeLoggerDelta
+)
+
+var timeModeNames = map[TimeMode]string{
+	TimeModeNone:        "none",
+	TimeModeDelta:       "delta",
+	TimeModeElapsed:     "elapsed",
+	TimeModeSinceStart:  "since-start",
+	TimeModeLoggerDelta: "logger-delta",
+}
+
+func (m TimeMode) String() string {
+	if name, found := timeModeNames[m]; found {
+		return name
+	}
+
+	return fmt.Sprintf("TimeMode(%d)", int(m))
+}
+
+// ParseTimeMode returns the time mode named `name`, one of `none`, `delta`, `elapsed`,
+// `since-start` or `logger-delta`.
+func ParseTimeMode(name string) (TimeMode, error) {
+	for mode, modeName := range timeModeNames {
+		if strings.EqualFold(name, modeName) {
+			return mode, nil
+		}
+	}
+
+	return TimeModeNone, fmt.Errorf("unknown time mode %q, valid values are none, delta, elapsed, since-start or logger-delta", name)
+}
+
+// relativeTime renders the relative time of the record according to the active time mode,
+// it must be called before `trackTimestamp` is called for the same record.
+func (p *Processor) relativeTime(rec *record) string {
+	timestamp := rec.timestamp
+
+	var reference *time.Time
+	elapsed := false
+
+	switch p.timeMode {
+	case TimeModeNone:
+		return ""
+
+	case TimeModeDelta:
+		reference = p.lastProcessedTimestamp
+
+	case TimeModeElapsed:
+		reference, elapsed = p.firstTimestamp, true
+
+	case TimeModeSinceStart:
+		if p.startMarker != nil && timestamp != nil && p.startMarker.MatchString(rec.message) {
+			p.startTimestamp = timestamp
+		}
+
+		reference, elapsed = p.startTimestamp, true
+		if reference == nil {
+			reference = p.firstTimestamp
+		}
+
+	case TimeModeLoggerDelta:
+		if last, found := p.lastTimestampByLogger[recordLoggerKey(rec)]; found {
+			reference = &last
+		}
+	}
+
+	if timestamp == nil {
+		return "-"
+	}
+
+	if reference == nil {
+		if !elapsed {
+			return "-"
+		}
+
+		// First line seen, it's the reference itself
+		reference = timestamp
+	}
+
+	if elapsed {
+		return "+" + durationToString(timestamp.Sub(*reference))
+	}
+
+	return durationToString(timestamp.Sub(*reference))
+}
+
+// trackTimestamp records the timestamp of the record as the last one seen, globally and
+// for its logger.
+func (p *Processor) trackTimestamp(rec *record) {
+	timestamp := rec.timestamp
+	if timestamp == nil {
+		return
+	}
+
+	if p.firstTimestamp == nil {
+		p.firstTimestamp = timestamp
+	}
+
+	p.lastProcessedTimestamp = timestamp
+
+	if p.timeMode == TimeModeLoggerDelta {
+		if p.lastTimestampByLogger == nil {
+			p.lastTimestampByLogger = map[string]time.Time{}
+		}
+
+		p.lastTimestampByLogger[recordLoggerKey(rec)] = *timestamp
+	}
+}
+
+func recordLoggerKey(rec *record) string {
+	if rec.logger == nil {
+		return ""
+	}
+
+	return *rec.logger
+}
@@ -0,0 +1,84 @@
+package zapp
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// truncateFields returns a copy of data where string values longer than `p.maxFieldLength`
+// bytes and arrays with more than `p.maxArrayItems` elements are elided. Keys configured
+// through `WithFullFields` (matched either by their name or their full dotted path) are
+// kept untouched, including all their children.
+//
+// The input map is never mutated, a copy is returned only if truncation is active.
+func (p *Processor) truncateFields(data map[string]interface{}) map[string]interface{} {
+	if p.maxFieldLength <= 0 && p.maxArrayItems <= 0 {
+		return data
+	}
+
+	return p.truncateObject("", data)
+}
+
+func (p *Processor) truncateObject(path string, data map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+
+		if p.fullFields[key] || p.fullFields[keyPath] {
+			out[key] = value
+			continue
+		}
+
+		out[key] = p.truncateValue(keyPath, value)
+	}
+
+	return out
+}
+
+func (p *Processor) truncateValue(path string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return truncateString(v, p.maxFieldLength)
+
+	case map[string]interface{}:
+		return p.truncateObject(path, v)
+
+	case []interface{}:
+		count := len(v)
+		if p.maxArrayItems > 0 && count > p.maxArrayItems {
+			count = p.maxArrayItems
+		}
+
+		out := make([]interface{}, 0, count+1)
+		for _, element := range v[:count] {
+			out = append(out, p.truncateValue(path, element))
+		}
+
+		if count < len(v) {
+			out = append(out, fmt.Sprintf("...%d more", len(v)-count))
+		}
+
+		return out
+	}
+
+	return value
+}
+
+// truncateString elides `value` if it's longer than `maxLength` bytes, the cut is always
+// performed on a valid UTF-8 boundary and the amount of bytes removed is appended, for
+// example `0x1234…(+4012 bytes)`. A `maxLength` of 0 or less means no truncation.
+func truncateString(value string, maxLength int) string {
+	if maxLength <= 0 || len(value) <= maxLength {
+		return value
+	}
+
+	cut := maxLength
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+
+	return fmt.Sprintf("%s…(+%d bytes)", value[:cut], len(value)-cut)
+}